@@ -0,0 +1,39 @@
+// Package sample holds the shapes and datasets shared by the package tests
+package sample
+
+import (
+	"math/big"
+
+	"simple-verifier-gnark/pkg/circuit"
+	"simple-verifier-gnark/pkg/witness"
+)
+
+// Shape returns a small generic shape
+// 16 rows of 4 columns, 3 handlers of 4 ops, 4 groups and 2 predicates per op.
+func Shape() circuit.Config {
+	return circuit.Config{MaxRows: 16, MaxCols: 4, MaxGroups: 4, MaxOps: 4, MaxHandlers: 3, MaxPredicates: 2, MaxMerkleFilters: 1}
+}
+
+// Dataset returns a builder for cfg holding 10 rows of columns id, a, key and a signed 16-bit delta
+// Row i is (i, 3i+1, i%3, 5-2i): key takes 0, 1 and 2, delta runs from 5 down to -13.
+func Dataset(cfg circuit.Config) *witness.Builder {
+	b := witness.New(cfg)
+
+	b.SetColumn(3, witness.Column{Bits: 16, Signed: true})
+
+	rows := make([][]*big.Int, 10)
+
+	for i := range rows {
+		rows[i] = witness.Ints(int64(i), int64(3*i+1), int64(i%3), int64(5-2*i))
+	}
+
+	b.SetRows(rows...)
+
+	return b
+}
+
+// Tiny returns a shape small enough to compile, set up and prove in every test run
+// 4 rows of 2 columns, one handler of 2 ops.
+func Tiny() circuit.Config {
+	return circuit.Config{MaxRows: 4, MaxCols: 2, MaxGroups: 2, MaxOps: 2, MaxHandlers: 1, MaxPredicates: 1}
+}
@@ -0,0 +1,114 @@
+// Package testutil holds the solver helpers shared by the package tests
+package testutil
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/constraint"
+	"github.com/consensys/gnark/constraint/solver"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/consensys/gnark/frontend/cs/scs"
+	"github.com/consensys/gnark/test"
+)
+
+// builders compile every test circuit to both constraint system kinds
+var builders = []struct {
+	name       string
+	newBuilder frontend.NewBuilder
+}{
+	{"r1cs", r1cs.NewBuilder[constraint.U64]},
+	{"scs", scs.NewBuilder[constraint.U64]},
+}
+
+// System is a compiled constraint system and its kind
+type System struct {
+	Name string
+	CCS  constraint.ConstraintSystem
+}
+
+// Compile compiles c to R1CS and SCS over BN254
+func Compile(t *testing.T, c frontend.Circuit) []System {
+	t.Helper()
+
+	field := ecc.BN254.ScalarField()
+
+	systems := make([]System, len(builders))
+
+	for i, b := range builders {
+		ccs, err := frontend.Compile(field, b.newBuilder, c)
+
+		if err != nil {
+			t.Fatalf("%s: %v", b.name, err)
+		}
+
+		systems[i] = System{b.name, ccs}
+	}
+
+	return systems
+}
+
+// AssertIsSolved checks whether assignment solves c on the test engine
+// Enough for circuits too large to compile in every test
+func AssertIsSolved(t *testing.T, c, assignment frontend.Circuit, want bool) {
+	t.Helper()
+
+	checkSolved(t, "test engine", test.IsSolved(c, assignment, ecc.BN254.ScalarField()), want)
+}
+
+// AssertSolvedBy checks whether assignment satisfies every compiled system
+// hints replace the prover's hints (see ForgeHint)
+func AssertSolvedBy(t *testing.T, systems []System, assignment frontend.Circuit, want bool, hints ...solver.Option) {
+	t.Helper()
+
+	w, err := frontend.NewWitness(assignment, ecc.BN254.ScalarField())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, s := range systems {
+		checkSolved(t, s.Name, s.CCS.IsSolved(w, hints...), want)
+	}
+}
+
+// AssertSolved checks whether assignment solves c, on the test engine and
+// on the R1CS and SCS constraint systems
+// The test engine always runs the honest hints, so it is skipped when hints are given.
+func AssertSolved(t *testing.T, c, assignment frontend.Circuit, want bool, hints ...solver.Option) {
+	t.Helper()
+
+	if len(hints) == 0 {
+		AssertIsSolved(t, c, assignment, want)
+	}
+
+	AssertSolvedBy(t, Compile(t, c), assignment, want, hints...)
+}
+
+// checkSolved reports a solver outcome that differs from want
+func checkSolved(t *testing.T, name string, err error, want bool) {
+	t.Helper()
+
+	if want && err != nil {
+		t.Errorf("%s: not solved: %v", name, err)
+	}
+
+	if !want && err == nil {
+		t.Errorf("%s: solved, expected unsatisfiable", name)
+	}
+}
+
+// ForgeHint runs the honest hint, then lets tamper rewrite its outputs
+func ForgeHint(hint solver.Hint, tamper func(field *big.Int, inputs, outputs []*big.Int)) solver.Option {
+	return solver.OverrideHint(solver.GetHintID(hint), func(field *big.Int, inputs, outputs []*big.Int) error {
+		if err := hint(field, inputs, outputs); err != nil {
+			return err
+		}
+
+		tamper(field, inputs, outputs)
+
+		return nil
+	})
+}
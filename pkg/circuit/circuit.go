@@ -10,39 +10,89 @@ import (
 // SimpleVerifierCircuit is the main circuit definition
 // Port of circom SimpleVerifier template
 //
+// Inputs are slices sized by Config; build both the circuit and its
+// assignment with New so the shapes match.
+//
+// With a Plan (see NewSpecialized) opcodes and column args are compile-time
+// constants and only the gadgets the plan uses are instantiated.
+//
 // Security Features:
 //   - Strict opcode validation (must match exactly 1 valid opcode)
 //   - Handler mask for inactive handler skip
 //   - GROUP BY validation with numGroups=0 bypass
+//   - Mandatory data commitment (Items + NR bound to public DataRoot)
+//   - Per-op row filter trees ANDed with the shared row mask
+//   - Op column args and filter columns must index Items
+//   - MERKLE16 column subtrees hashed once and shared across handlers
+//   - Per-column bit width range checks (aggregates never wrap the field)
+//   - Two's-complement signed columns decoded before any aggregate
+//   - Fixed-point columns: SUM_PRODUCT divides back by the Y scale with a proven remainder
 type SimpleVerifierCircuit struct {
+	// Config: circuit dimensions (not a circuit input)
+	Config Config `gnark:"-"`
+
+	// Plan: optional fixed query plan (not a circuit input, nil = any query)
+	Plan *Plan `gnark:"-"`
+
 	// =====================================
 	// Public Inputs
 	// =====================================
 
 	// HandlerNCs: NC (number of columns) per handler
-	HandlerNCs [lib.MAX_HANDLERS]frontend.Variable `gnark:",public"`
+	HandlerNCs []frontend.Variable `gnark:",public"`
 
 	// HandlerStartIndex: starting column index per handler
-	HandlerStartIndex [lib.MAX_HANDLERS]frontend.Variable `gnark:",public"`
+	HandlerStartIndex []frontend.Variable `gnark:",public"`
 
 	// OpCodes: ops per handler [handler][op]
-	OpCodes [lib.MAX_HANDLERS][lib.MAX_OPS]frontend.Variable `gnark:",public"`
+	OpCodes [][]frontend.Variable `gnark:",public"`
 
 	// OpArgs: [colX, colY] per op [handler][op][2]
-	OpArgs [lib.MAX_HANDLERS][lib.MAX_OPS][2]frontend.Variable `gnark:",public"`
+	OpArgs [][][2]frontend.Variable `gnark:",public"`
+
+	// FilterOps: leaf predicates per op (PRED_* constants) [handler][op][pred]
+	FilterOps [][][]frontend.Variable `gnark:",public"`
+
+	// FilterCols: leaf predicate column k [handler][op][pred]
+	FilterCols [][][]frontend.Variable `gnark:",public"`
+
+	// FilterArgs: leaf predicate operands [a, b] [handler][op][pred][2]
+	FilterArgs [][][][2]frontend.Variable `gnark:",public"`
+
+	// FilterJoins: JOIN_AND / JOIN_OR per internal tree node (heap order) [handler][op][node]
+	// Empty with MaxPredicates = 1 (single-leaf trees have no join, see Joins)
+	FilterJoins [][][]frontend.Variable `gnark:",public"`
+
+	// FilterNots: 1 negates a tree node (heap order, leaves last) [handler][op][node]
+	FilterNots [][][]frontend.Variable `gnark:",public"`
 
 	// Results: expected results [handler][op][group]
-	Results [lib.MAX_HANDLERS][lib.MAX_OPS][lib.MAX_GROUPS]frontend.Variable `gnark:",public"`
+	Results [][][]frontend.Variable `gnark:",public"`
 
 	// GroupKeys: PUBLIC group keys [handler][op][group]
-	GroupKeys [lib.MAX_HANDLERS][lib.MAX_OPS][lib.MAX_GROUPS]frontend.Variable `gnark:",public"`
+	GroupKeys [][][]frontend.Variable `gnark:",public"`
 
 	// NumGroups: groups per SUM_BY op [handler][op]
-	NumGroups [lib.MAX_HANDLERS][lib.MAX_OPS]frontend.Variable `gnark:",public"`
+	NumGroups [][]frontend.Variable `gnark:",public"`
 
 	// NumHandlers: actual number of handlers
 	NumHandlers frontend.Variable `gnark:",public"`
 
+	// ColumnBits: declared bit width per column (0..VALUE_BITS)
+	// Every valid cell is range checked: Items[col][row] < 2^ColumnBits[col]
+	ColumnBits []frontend.Variable `gnark:",public"`
+
+	// ColumnSigned: 1 if the column holds two's-complement signed integers of ColumnBits width
+	ColumnSigned []frontend.Variable `gnark:",public"`
+
+	// ColumnScales: fixed-point scale per column (1 = integer, e.g. 1000000 = 6 decimals)
+	// SUM/AVG results keep the column scale; SUM_PRODUCT is divided back to the X scale
+	ColumnScales []frontend.Variable `gnark:",public"`
+
+	// DataRoot: commitment to the full Items matrix and NR
+	// DataRoot = Poseidon2(Merkle16Ordered(Items), NR)
+	DataRoot frontend.Variable `gnark:",public"`
+
 	// =====================================
 	// Private Inputs (SHARED across handlers)
 	// =====================================
@@ -51,123 +101,576 @@ type SimpleVerifierCircuit struct {
 	NR frontend.Variable
 
 	// Items: matrix data (shared) [col][row]
-	Items [lib.MAX_COLS][lib.MAX_ROWS]frontend.Variable
+	Items [][]frontend.Variable
+}
+
+// New allocates a SimpleVerifierCircuit of the given shape
+// Every input slice is sized from cfg and zero-filled, so the result is usable
+// both as the circuit to compile and as the base of an assignment.
+func New(cfg Config) *SimpleVerifierCircuit {
+	c := &SimpleVerifierCircuit{
+		Config:            cfg,
+		HandlerNCs:        zeros(cfg.MaxHandlers),
+		HandlerStartIndex: zeros(cfg.MaxHandlers),
+		OpCodes:           make([][]frontend.Variable, cfg.MaxHandlers),
+		OpArgs:            make([][][2]frontend.Variable, cfg.MaxHandlers),
+		FilterOps:         make([][][]frontend.Variable, cfg.MaxHandlers),
+		FilterCols:        make([][][]frontend.Variable, cfg.MaxHandlers),
+		FilterArgs:        make([][][][2]frontend.Variable, cfg.MaxHandlers),
+		FilterNots:        make([][][]frontend.Variable, cfg.MaxHandlers),
+		Results:           make([][][]frontend.Variable, cfg.MaxHandlers),
+		GroupKeys:         make([][][]frontend.Variable, cfg.MaxHandlers),
+		NumGroups:         make([][]frontend.Variable, cfg.MaxHandlers),
+		NumHandlers:       0,
+		ColumnBits:        zeros(cfg.MaxCols),
+		ColumnSigned:      zeros(cfg.MaxCols),
+		ColumnScales:      zeros(cfg.MaxCols),
+		DataRoot:          0,
+		NR:                0,
+		Items:             make([][]frontend.Variable, cfg.MaxCols),
+	}
+
+	// gnark warns on every zero-length []frontend.Variable: a single-leaf tree keeps none
+	if cfg.MaxPredicates > 1 {
+		c.FilterJoins = make([][][]frontend.Variable, cfg.MaxHandlers)
+	}
+
+	for h := 0; h < cfg.MaxHandlers; h++ {
+		c.OpCodes[h] = zeros(cfg.MaxOps)
+
+		c.OpArgs[h] = make([][2]frontend.Variable, cfg.MaxOps)
+
+		c.FilterOps[h] = make([][]frontend.Variable, cfg.MaxOps)
+
+		c.FilterCols[h] = make([][]frontend.Variable, cfg.MaxOps)
+
+		c.FilterArgs[h] = make([][][2]frontend.Variable, cfg.MaxOps)
+
+		if c.FilterJoins != nil {
+			c.FilterJoins[h] = make([][]frontend.Variable, cfg.MaxOps)
+		}
+
+		c.FilterNots[h] = make([][]frontend.Variable, cfg.MaxOps)
+
+		c.Results[h] = make([][]frontend.Variable, cfg.MaxOps)
+
+		c.GroupKeys[h] = make([][]frontend.Variable, cfg.MaxOps)
+
+		c.NumGroups[h] = zeros(cfg.MaxOps)
+
+		for op := 0; op < cfg.MaxOps; op++ {
+			c.OpArgs[h][op] = [2]frontend.Variable{0, 0}
+
+			c.FilterOps[h][op] = zeros(cfg.MaxPredicates)
+
+			c.FilterCols[h][op] = zeros(cfg.MaxPredicates)
+
+			c.FilterArgs[h][op] = make([][2]frontend.Variable, cfg.MaxPredicates)
+
+			for p := 0; p < cfg.MaxPredicates; p++ {
+				c.FilterArgs[h][op][p] = [2]frontend.Variable{0, 0}
+			}
+
+			if c.FilterJoins != nil {
+				c.FilterJoins[h][op] = zeros(cfg.MaxPredicates - 1)
+			}
+
+			c.FilterNots[h][op] = zeros(2*cfg.MaxPredicates - 1)
+
+			c.Results[h][op] = zeros(cfg.MaxGroups)
+
+			c.GroupKeys[h][op] = zeros(cfg.MaxGroups)
+		}
+	}
+
+	for col := 0; col < cfg.MaxCols; col++ {
+		c.Items[col] = zeros(cfg.MaxRows)
+	}
+
+	return c
+}
+
+// Joins returns the tree joins of op slot [h][op] (FilterJoins), nil with MaxPredicates = 1
+func (c *SimpleVerifierCircuit) Joins(h, op int) []frontend.Variable {
+	if c.FilterJoins == nil {
+		return nil
+	}
+
+	return c.FilterJoins[h][op]
+}
+
+// zeros returns n zero variables
+func zeros(n int) []frontend.Variable {
+	v := make([]frontend.Variable, n)
+
+	for i := range v {
+		v[i] = 0
+	}
+
+	return v
+}
+
+// opFlags holds the one-hot opcode selectors of a single op slot
+type opFlags struct {
+	isNoop    frontend.Variable
+	isMerkle  frontend.Variable
+	isCount   frontend.Variable
+	isSum     frontend.Variable
+	isMin     frontend.Variable
+	isMax     frontend.Variable
+	isAvg     frontend.Variable
+	isSumProd frontend.Variable
+	isSumBy   frontend.Variable
+	isMinBy   frontend.Variable
+	isMaxBy   frontend.Variable
+	isCountBy frontend.Variable
+}
+
+// matchOpCode compares opCode against every known opcode
+// Returns the flags and their sum (1 iff opCode is valid)
+func matchOpCode(api frontend.API, opCode frontend.Variable) (opFlags, frontend.Variable) {
+	flags := opFlags{
+		isNoop:    lib.IsEqual(api, opCode, frontend.Variable(lib.OP_NOOP)),
+		isMerkle:  lib.IsEqual(api, opCode, frontend.Variable(lib.OP_MERKLE16)),
+		isCount:   lib.IsEqual(api, opCode, frontend.Variable(lib.OP_COUNT)),
+		isSum:     lib.IsEqual(api, opCode, frontend.Variable(lib.OP_SUM_COL)),
+		isMin:     lib.IsEqual(api, opCode, frontend.Variable(lib.OP_MIN_COL)),
+		isMax:     lib.IsEqual(api, opCode, frontend.Variable(lib.OP_MAX_COL)),
+		isAvg:     lib.IsEqual(api, opCode, frontend.Variable(lib.OP_AVG_COL)),
+		isSumProd: lib.IsEqual(api, opCode, frontend.Variable(lib.OP_SUM_PRODUCT)),
+		isSumBy:   lib.IsEqual(api, opCode, frontend.Variable(lib.OP_SUM_COL_BY)),
+		isMinBy:   lib.IsEqual(api, opCode, frontend.Variable(lib.OP_MIN_COL_BY)),
+		isMaxBy:   lib.IsEqual(api, opCode, frontend.Variable(lib.OP_MAX_COL_BY)),
+		isCountBy: lib.IsEqual(api, opCode, frontend.Variable(lib.OP_COUNT_BY)),
+	}
+
+	validOpSum := api.Add(
+		flags.isNoop,
+		flags.isMerkle,
+		flags.isCount,
+		flags.isSum,
+		flags.isMin,
+		flags.isMax,
+		flags.isAvg,
+		flags.isSumProd,
+		flags.isSumBy,
+		flags.isMinBy,
+		flags.isMaxBy,
+		flags.isCountBy,
+	)
+
+	return flags, validOpSum
 }
 
 // Define implements frontend.Circuit
 func (c *SimpleVerifierCircuit) Define(api frontend.API) error {
+	cfg := c.Config
+
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	plan := c.Plan
+
+	if plan != nil {
+		if err := plan.Validate(cfg); err != nil {
+			return err
+		}
+	}
+
+	nLevels := cfg.NLevels()
+
+	// Step 0: Bind private data to the public commitment
+	dataRoot := operators.DataCommitment(api, lib.FlattenItems(c.Items), c.NR, nLevels)
+
+	api.AssertIsEqual(dataRoot, c.DataRoot)
+
 	// Step 1: Create row mask (shared)
-	rowMask := lib.RowMask(api, c.NR, lib.MAX_ROWS)
+	rowMask := lib.RowMask(api, c.NR, cfg.MaxRows)
+
+	// Step 2: Range check every valid cell and decode signed columns
+	// Aggregates read the decoded values; MERKLE16 commits the raw Items
+	values := lib.DecodeColumns(api, c.Items, c.ColumnBits, c.ColumnSigned, rowMask)
 
-	// Step 2: Create column masks per handler
-	colMasks := make([][]frontend.Variable, lib.MAX_HANDLERS)
+	// Column extraction at signal indices (built on first use, see Config.Selector)
+	sel := &lazySelector{kind: cfg.Selector, items: values}
+
+	lib.AssertColumnScales(api, c.ColumnScales)
+
+	// Step 3: Create column masks per handler (only MERKLE16 reads them)
+	colMasks := make([][]frontend.Variable, cfg.MaxHandlers)
+
+	for h := 0; h < cfg.MaxHandlers; h++ {
+		if !c.handlerUses(h, lib.OP_MERKLE16) {
+			continue
+		}
 
-	for h := 0; h < lib.MAX_HANDLERS; h++ {
-		colMasks[h] = lib.ColumnMaskWithStart(api, c.HandlerStartIndex[h], c.HandlerNCs[h], lib.MAX_COLS)
+		colMasks[h] = lib.ColumnMaskWithStart(api, c.HandlerStartIndex[h], c.HandlerNCs[h], cfg.MaxCols)
 	}
 
-	// Step 3: Handler mask (skip inactive handlers)
-	handlerMask := make([]frontend.Variable, lib.MAX_HANDLERS)
+	// Step 4: Handler mask (skip inactive handlers)
+	// With a plan the number of handlers is a constant
+	handlerMask := make([]frontend.Variable, cfg.MaxHandlers)
 
-	for h := 0; h < lib.MAX_HANDLERS; h++ {
-		handlerMask[h] = lib.LessThan(api, frontend.Variable(h), c.NumHandlers, 8)
+	if plan != nil {
+		api.AssertIsEqual(c.NumHandlers, len(plan.Handlers))
 	}
 
-	// Step 4: MERKLE16 instances per handler
-	merkleRoots := make([]frontend.Variable, lib.MAX_HANDLERS)
+	for h := 0; h < cfg.MaxHandlers; h++ {
+		if plan != nil {
+			handlerMask[h] = boolConst(h < len(plan.Handlers))
+
+			continue
+		}
+
+		handlerMask[h] = lib.LessThan(api, frontend.Variable(h), c.NumHandlers, lib.IndexBits(cfg.MaxHandlers))
+	}
+
+	// Step 5: OpCode matching per handler per op
+	// With a plan the flags are constants and the public opcodes/args must match them
+	flags := make([][]opFlags, cfg.MaxHandlers)
+
+	for h := 0; h < cfg.MaxHandlers; h++ {
+		flags[h] = make([]opFlags, cfg.MaxOps)
+
+		for op := 0; op < cfg.MaxOps; op++ {
+			if plan != nil {
+				planOp := plan.op(h, op)
+
+				flags[h][op] = constFlags(planOp.OpCode)
 
-	for h := 0; h < lib.MAX_HANDLERS; h++ {
-		flatItems := lib.FlattenItems(c.Items)
+				if h < len(plan.Handlers) {
+					api.AssertIsEqual(c.OpCodes[h][op], planOp.OpCode)
 
-		flatMask := lib.CreateFlatMask(api, rowMask, colMasks[h])
+					api.AssertIsEqual(c.OpArgs[h][op][0], planOp.ColX)
 
-		merkleRoots[h] = operators.Merkle16OrderedWithMask(api, flatItems, flatMask, lib.N_LEVELS)
+					api.AssertIsEqual(c.OpArgs[h][op][1], planOp.ColY)
+				}
+
+				continue
+			}
+
+			var validOpSum frontend.Variable
+
+			flags[h][op], validOpSum = matchOpCode(api, c.OpCodes[h][op])
+
+			// STRICT: Validate opcode is exactly 1 valid type
+			opValidationTerm := api.Mul(api.Sub(validOpSum, 1), handlerMask[h])
+
+			api.AssertIsEqual(opValidationTerm, 0)
+
+			// Column args must index Items: the selectors read an out-of-range arg as an all-zero column
+			for arg := 0; arg < 2; arg++ {
+				assertColumn(api, api.Mul(c.OpArgs[h][op][arg], handlerMask[h]), cfg.MaxCols)
+			}
+		}
 	}
 
-	// Step 5: COUNT instance (shared)
-	countResult := operators.Count(api, rowMask)
+	// Step 6: Row filters per handler per op (filtered mask = rowMask AND predicate tree)
+	filterMasks := make([][][]frontend.Variable, cfg.MaxHandlers)
+
+	for h := 0; h < cfg.MaxHandlers; h++ {
+		filterMasks[h] = make([][]frontend.Variable, cfg.MaxOps)
 
-	// Step 6: SUM operators per handler per op
-	sumResults := make([][]frontend.Variable, lib.MAX_HANDLERS)
+		for op := 0; op < cfg.MaxOps; op++ {
+			if !plan.filtered(h, op) {
+				// Unfiltered plan op: the public filter must be empty (selects every row)
+				if h < len(plan.Handlers) {
+					c.assertNoFilter(api, h, op, 1)
+				}
 
-	for h := 0; h < lib.MAX_HANDLERS; h++ {
-		sumResults[h] = make([]frontend.Variable, lib.MAX_OPS)
+				filterMasks[h][op] = rowMask
 
-		for op := 0; op < lib.MAX_OPS; op++ {
-			sumResults[h][op] = operators.SumColumn(api, c.Items, c.OpArgs[h][op][0], rowMask)
+				continue
+			}
+
+			tree := lib.FilterTree{
+				Ops:     c.FilterOps[h][op],
+				Columns: make([][]frontend.Variable, cfg.MaxPredicates),
+				Args:    c.FilterArgs[h][op],
+				Joins:   c.Joins(h, op),
+				Nots:    c.FilterNots[h][op],
+			}
+
+			for p := 0; p < cfg.MaxPredicates; p++ {
+				tree.Columns[p] = sel.Column(api, c.FilterCols[h][op][p])
+
+				// Like the op args, filter columns of active handlers must index Items
+				assertColumn(api, api.Mul(c.FilterCols[h][op][p], handlerMask[h]), cfg.MaxCols)
+			}
+
+			var validFilter frontend.Variable
+
+			filterMasks[h][op], validFilter = lib.FilterTreeMask(api, tree, rowMask)
+
+			filterValidationTerm := api.Mul(api.Sub(validFilter, 1), handlerMask[h])
+
+			api.AssertIsEqual(filterValidationTerm, 0)
+		}
+	}
+
+	// Step 7: MERKLE16 roots per handler per op
+	// Column subtrees of the NR rows are hashed once and shared: each handler's root
+	// only re-hashes the nodes spanning its column range (see operators.Merkle16Columns).
+	// A filtered MERKLE16 op hashes the rows of its own filter: a plan builds one
+	// tree per Filtered op, the generic circuit MaxMerkleFilters trees (see merkleSlots).
+	var sharedTree *operators.Merkle16Columns
+
+	var filteredRoots [][]frontend.Variable
+
+	merkleSlots := make([][][]frontend.Variable, cfg.MaxHandlers)
+
+	if plan == nil {
+		sharedTree = operators.NewMerkle16Columns(api, c.Items, rowMask, nLevels)
+
+		merkleSlots = c.merkleSlots(api, flags, handlerMask)
+
+		filteredRoots = make([][]frontend.Variable, cfg.MaxMerkleFilters)
+
+		for t := 0; t < cfg.MaxMerkleFilters; t++ {
+			// Tree t hashes the rows of the filter routed to it (none: every cell zeroed)
+			slotMask := zeros(cfg.MaxRows)
+
+			for h := 0; h < cfg.MaxHandlers; h++ {
+				for op := 0; op < cfg.MaxOps; op++ {
+					for row := 0; row < cfg.MaxRows; row++ {
+						slotMask[row] = api.Add(slotMask[row], api.Mul(merkleSlots[h][op][t], filterMasks[h][op][row]))
+					}
+				}
+			}
+
+			tree := operators.NewMerkle16Columns(api, c.Items, slotMask, nLevels)
+
+			filteredRoots[t] = make([]frontend.Variable, cfg.MaxHandlers)
+
+			for h := 0; h < cfg.MaxHandlers; h++ {
+				filteredRoots[t][h] = tree.Root(api, colMasks[h])
+			}
 		}
 	}
 
-	// Step 7: SUM_BY operators per handler per op
-	sumByResults := make([][]operators.SumColumnByGroupResult, lib.MAX_HANDLERS)
+	merkleRoots := make([][]frontend.Variable, cfg.MaxHandlers)
+
+	for h := 0; h < cfg.MaxHandlers; h++ {
+		merkleRoots[h] = zeros(cfg.MaxOps)
+
+		var sharedRoot frontend.Variable
+
+		for op := 0; op < cfg.MaxOps; op++ {
+			if !plan.uses(h, op, lib.OP_MERKLE16) {
+				continue
+			}
+
+			if plan != nil && plan.filtered(h, op) {
+				merkleRoots[h][op] = operators.NewMerkle16Columns(api, c.Items, filterMasks[h][op], nLevels).Root(api, colMasks[h])
 
-	for h := 0; h < lib.MAX_HANDLERS; h++ {
-		sumByResults[h] = make([]operators.SumColumnByGroupResult, lib.MAX_OPS)
+				continue
+			}
+
+			if sharedTree == nil {
+				sharedTree = operators.NewMerkle16Columns(api, c.Items, rowMask, nLevels)
+			}
+
+			// MERKLE16 ops of one handler share its column range
+			if sharedRoot == nil {
+				sharedRoot = sharedTree.Root(api, colMasks[h])
+			}
+
+			merkleRoots[h][op] = sharedRoot
+
+			for t := range filteredRoots {
+				merkleRoots[h][op] = api.Add(merkleRoots[h][op], api.Mul(merkleSlots[h][op][t], api.Sub(filteredRoots[t][h], sharedRoot)))
+			}
+		}
+	}
+
+	// Step 8: COUNT, SUM operators per handler per op
+	countResults := make([][]frontend.Variable, cfg.MaxHandlers)
+
+	sumResults := make([][]frontend.Variable, cfg.MaxHandlers)
+
+	for h := 0; h < cfg.MaxHandlers; h++ {
+		countResults[h] = make([]frontend.Variable, cfg.MaxOps)
+
+		sumResults[h] = make([]frontend.Variable, cfg.MaxOps)
+
+		for op := 0; op < cfg.MaxOps; op++ {
+			countResults[h][op] = frontend.Variable(0)
+
+			sumResults[h][op] = frontend.Variable(0)
+
+			if plan.uses(h, op, lib.OP_COUNT, lib.OP_AVG_COL) {
+				countResults[h][op] = operators.Count(api, filterMasks[h][op])
+			}
+
+			if plan == nil {
+				sumResults[h][op] = operators.SumColumn(api, sel, c.OpArgs[h][op][0], filterMasks[h][op])
+			} else if plan.uses(h, op, lib.OP_SUM_COL, lib.OP_AVG_COL) {
+				sumResults[h][op] = lib.MaskedSum(api, c.column(api, sel, values, h, op, 0), filterMasks[h][op])
+			}
+		}
+	}
+
+	// Step 9: AVG operators per handler per op
+	// Inputs are switched off for other opcodes so the quotient range check always holds
+	avgResults := make([][]operators.AverageResult, cfg.MaxHandlers)
+
+	for h := 0; h < cfg.MaxHandlers; h++ {
+		avgResults[h] = make([]operators.AverageResult, cfg.MaxOps)
+
+		for op := 0; op < cfg.MaxOps; op++ {
+			if !plan.uses(h, op, lib.OP_AVG_COL) {
+				avgResults[h][op] = operators.AverageResult{Quotient: 0, Remainder: 0}
+
+				continue
+			}
+
+			avgSum := api.Mul(sumResults[h][op], flags[h][op].isAvg)
+
+			avgCount := api.Mul(countResults[h][op], flags[h][op].isAvg)
+
+			avgResults[h][op] = operators.Average(api, avgSum, avgCount)
+		}
+	}
+
+	// Step 10: MIN/MAX operators per handler per op
+	// Row mask is switched off for other opcodes so their columns are not range bound
+	minMaxResults := make([][]operators.MinMaxColumnResult, cfg.MaxHandlers)
+
+	for h := 0; h < cfg.MaxHandlers; h++ {
+		minMaxResults[h] = make([]operators.MinMaxColumnResult, cfg.MaxOps)
+
+		for op := 0; op < cfg.MaxOps; op++ {
+			if !plan.uses(h, op, lib.OP_MIN_COL, lib.OP_MAX_COL) {
+				minMaxResults[h][op] = operators.MinMaxColumnResult{Min: 0, Max: 0}
+
+				continue
+			}
+
+			isMinMax := api.Add(flags[h][op].isMin, flags[h][op].isMax)
+
+			minMaxMask := lib.ScaleMask(api, filterMasks[h][op], isMinMax)
+
+			if plan == nil {
+				minMaxResults[h][op] = operators.MinMaxColumn(api, sel, c.OpArgs[h][op][0], minMaxMask)
+			} else {
+				// Constant column: one column matrix, the selector folds away
+				column := lib.NewMuxSelector([][]frontend.Variable{c.column(api, sel, values, h, op, 0)})
+
+				minMaxResults[h][op] = operators.MinMaxColumn(api, column, 0, minMaxMask)
+			}
+		}
+	}
+
+	// Step 11: SUM_PRODUCT and SUM_BY / MIN_BY / MAX_BY / COUNT_BY operators per handler per op
+	// Columns X and Y are selected once per op and shared by these operators
+	sumProductResults := make([][]operators.SumProductResult, cfg.MaxHandlers)
+
+	sumByResults := make([][]operators.SumColumnByGroupResult, cfg.MaxHandlers)
+
+	for h := 0; h < cfg.MaxHandlers; h++ {
+		sumProductResults[h] = make([]operators.SumProductResult, cfg.MaxOps)
+
+		sumByResults[h] = make([]operators.SumColumnByGroupResult, cfg.MaxOps)
+
+		for op := 0; op < cfg.MaxOps; op++ {
+			usesSumProd := plan.uses(h, op, lib.OP_SUM_PRODUCT)
+
+			usesGroupBy := plan.uses(h, op, lib.OP_SUM_COL_BY, lib.OP_MIN_COL_BY, lib.OP_MAX_COL_BY, lib.OP_COUNT_BY)
+
+			sumProductResults[h][op] = operators.SumProductResult{Quotient: 0, Remainder: 0}
+
+			sumByResults[h][op] = emptyGroupResult(cfg.MaxGroups)
+
+			if !usesSumProd && !usesGroupBy {
+				continue
+			}
+
+			valuesX := c.column(api, sel, values, h, op, 0)
+
+			valuesY := c.column(api, sel, values, h, op, 1)
+
+			if usesSumProd {
+				// Other opcodes divide by 1 so an out-of-range colY cannot select a zero scale
+				scaleY := api.Select(flags[h][op].isSumProd, c.columnScale(api, h, op), 1)
+
+				sumProductResults[h][op] = operators.SumProduct(api, valuesX, valuesY, filterMasks[h][op], scaleY)
+			}
+
+			if !usesGroupBy {
+				continue
+			}
 
-		for op := 0; op < lib.MAX_OPS; op++ {
 			sumByResults[h][op] = operators.SumColumnByGroup(
 				api,
-				c.Items,
-				c.OpArgs[h][op][0],
-				c.OpArgs[h][op][1],
-				rowMask,
+				valuesX,
+				valuesY,
+				filterMasks[h][op],
 				c.GroupKeys[h][op],
 				c.NumGroups[h][op],
+				api.Add(flags[h][op].isMinBy, flags[h][op].isMaxBy),
 			)
 		}
 	}
 
-	// Step 8: OpCode matching and result multiplexing
-	for h := 0; h < lib.MAX_HANDLERS; h++ {
-		for op := 0; op < lib.MAX_OPS; op++ {
-			// OpCode matching
-			isNoop := lib.IsEqual(api, c.OpCodes[h][op], frontend.Variable(lib.OP_NOOP))
+	// Step 12: Result multiplexing
+	for h := 0; h < cfg.MaxHandlers; h++ {
+		for op := 0; op < cfg.MaxOps; op++ {
+			f := flags[h][op]
 
-			isMerkle := lib.IsEqual(api, c.OpCodes[h][op], frontend.Variable(lib.OP_MERKLE16))
-
-			isCount := lib.IsEqual(api, c.OpCodes[h][op], frontend.Variable(lib.OP_COUNT))
+			// Scalar ops go to index 0
+			resultNoop := frontend.Variable(0)
 
-			isSum := lib.IsEqual(api, c.OpCodes[h][op], frontend.Variable(lib.OP_SUM_COL))
+			resultMerkle := api.Mul(merkleRoots[h][op], f.isMerkle)
 
-			isSumBy := lib.IsEqual(api, c.OpCodes[h][op], frontend.Variable(lib.OP_SUM_COL_BY))
+			resultCount := api.Mul(countResults[h][op], f.isCount)
 
-			// STRICT: Validate opcode is exactly 1 valid type
-			validOpSum := api.Add(
-				api.Add(api.Add(api.Add(isNoop, isMerkle), isCount), isSum),
-				isSumBy,
-			)
+			resultSum := api.Mul(sumResults[h][op], f.isSum)
 
-			opValidationTerm := api.Mul(api.Sub(validOpSum, 1), handlerMask[h])
+			resultMin := api.Mul(minMaxResults[h][op].Min, f.isMin)
 
-			api.AssertIsEqual(opValidationTerm, 0)
+			resultMax := api.Mul(minMaxResults[h][op].Max, f.isMax)
 
-			// Result multiplexing (scalar ops go to index 0)
-			resultNoop := frontend.Variable(0)
+			// SUM_PRODUCT: rescaled quotient in slot 0, remainder in slot 1
+			resultSumProd := api.Mul(sumProductResults[h][op].Quotient, f.isSumProd)
 
-			resultMerkle := api.Mul(merkleRoots[h], isMerkle)
+			resultSumProdRem := api.Mul(sumProductResults[h][op].Remainder, f.isSumProd)
 
-			resultCount := api.Mul(countResult, isCount)
+			// AVG: quotient in slot 0, remainder in slot 1
+			resultAvg := api.Mul(avgResults[h][op].Quotient, f.isAvg)
 
-			resultSum := api.Mul(sumResults[h][op], isSum)
+			resultAvgRem := api.Mul(avgResults[h][op].Remainder, f.isAvg)
 
-			// Per-group comparison for SUM_BY, slot 0 for other ops
-			for g := 0; g < lib.MAX_GROUPS; g++ {
-				resultSumByG := api.Mul(sumByResults[h][op].GroupSums[g], isSumBy)
+			// Per-group comparison for GROUP BY ops, slot 0 for other ops
+			for g := 0; g < cfg.MaxGroups; g++ {
+				resultGroupG := api.Add(
+					api.Mul(sumByResults[h][op].GroupSums[g], f.isSumBy),
+					api.Mul(sumByResults[h][op].GroupMins[g], f.isMinBy),
+					api.Mul(sumByResults[h][op].GroupMaxs[g], f.isMaxBy),
+					api.Mul(sumByResults[h][op].GroupCounts[g], f.isCountBy),
+				)
 
 				var computedResult frontend.Variable
 
 				if g == 0 {
-					// Slot 0: scalar ops OR first group of SUM_BY
+					// Slot 0: scalar ops OR first group of GROUP BY ops
 					computedResult = api.Add(
-						api.Add(
-							api.Add(api.Add(resultNoop, resultMerkle), resultCount),
-							resultSum,
-						),
-						resultSumByG,
+						resultNoop,
+						resultMerkle,
+						resultCount,
+						resultSum,
+						resultMin,
+						resultMax,
+						resultAvg,
+						resultSumProd,
+						resultGroupG,
 					)
+				} else if g == 1 {
+					// Slot 1: AVG / SUM_PRODUCT remainder OR second group of GROUP BY ops
+					computedResult = api.Add(resultAvgRem, resultSumProdRem, resultGroupG)
 				} else {
-					// Slot 1+: only SUM_BY has values
-					computedResult = resultSumByG
+					// Slot 2+: only GROUP BY ops have values
+					computedResult = resultGroupG
 				}
 
 				// Verify: (computed - expected) * handlerMask === 0
@@ -180,3 +683,156 @@ func (c *SimpleVerifierCircuit) Define(api frontend.API) error {
 
 	return nil
 }
+
+// handlerUses reports whether any op slot of handler h may run opCode
+func (c *SimpleVerifierCircuit) handlerUses(h int, opCode int) bool {
+	for op := 0; op < c.Config.MaxOps; op++ {
+		if c.Plan.uses(h, op, opCode) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// column returns the decoded column OpArgs[h][op][arg]
+// With a plan the index is a constant and sel is not used
+func (c *SimpleVerifierCircuit) column(api frontend.API, sel lib.ColumnSelector, values [][]frontend.Variable, h, op, arg int) []frontend.Variable {
+	if c.Plan == nil {
+		return sel.Column(api, c.OpArgs[h][op][arg])
+	}
+
+	planOp := c.Plan.op(h, op)
+
+	if arg == 0 {
+		return values[planOp.ColX]
+	}
+
+	return values[planOp.ColY]
+}
+
+// columnScale returns ColumnScales[OpArgs[h][op][1]]
+func (c *SimpleVerifierCircuit) columnScale(api frontend.API, h, op int) frontend.Variable {
+	if c.Plan == nil {
+		return lib.Selector(api, c.ColumnScales, c.OpArgs[h][op][1])
+	}
+
+	return c.ColumnScales[c.Plan.op(h, op).ColY]
+}
+
+// assertNoFilter asserts that the public filter of a slot selects every row
+// (PRED_NONE leaves, AND joins, no NOT) whenever enabled is 1
+func (c *SimpleVerifierCircuit) assertNoFilter(api frontend.API, h, op int, enabled frontend.Variable) {
+	for p := range c.FilterOps[h][op] {
+		api.AssertIsEqual(api.Mul(api.Sub(c.FilterOps[h][op][p], lib.PRED_NONE), enabled), 0)
+	}
+
+	for _, join := range c.Joins(h, op) {
+		api.AssertIsEqual(api.Mul(api.Sub(join, lib.JOIN_AND), enabled), 0)
+	}
+
+	for node := range c.FilterNots[h][op] {
+		api.AssertIsEqual(api.Mul(c.FilterNots[h][op][node], enabled), 0)
+	}
+}
+
+// assertColumn checks that a public column index selects a column of Items
+// ToBinary bounds it first, LessThan alone reads p - k as below maxCols
+func assertColumn(api frontend.API, col frontend.Variable, maxCols int) {
+	bits := lib.IndexBits(maxCols)
+
+	api.ToBinary(col, bits)
+
+	api.AssertIsEqual(lib.LessThan(api, col, maxCols, bits), 1)
+}
+
+// merkleSlots routes the filtered MERKLE16 ops of the generic circuit to the
+// MaxMerkleFilters filtered trees: slots[h][op][t] is 1 iff op is the t-th active
+// MERKLE16 op (in handler then op order) whose public filter is not empty.
+// More filtered MERKLE16 ops than trees are rejected.
+func (c *SimpleVerifierCircuit) merkleSlots(api frontend.API, flags [][]opFlags, handlerMask []frontend.Variable) [][][]frontend.Variable {
+	cfg := c.Config
+
+	slots := make([][][]frontend.Variable, cfg.MaxHandlers)
+
+	var count frontend.Variable = 0
+
+	for h := 0; h < cfg.MaxHandlers; h++ {
+		slots[h] = make([][]frontend.Variable, cfg.MaxOps)
+
+		for op := 0; op < cfg.MaxOps; op++ {
+			filtered := api.Mul(flags[h][op].isMerkle, handlerMask[h], api.Sub(1, c.emptyFilter(api, h, op)))
+
+			slots[h][op] = make([]frontend.Variable, cfg.MaxMerkleFilters)
+
+			for t := range slots[h][op] {
+				slots[h][op][t] = api.Mul(filtered, lib.IsEqual(api, count, t))
+			}
+
+			count = api.Add(count, filtered)
+		}
+	}
+
+	fits := lib.LessThan(api, count, cfg.MaxMerkleFilters+1, lib.IndexBits(cfg.MaxHandlers*cfg.MaxOps+1))
+
+	api.AssertIsEqual(fits, 1)
+
+	return slots
+}
+
+// emptyFilter returns 1 iff the public filter of op slot [h][op] is empty
+// (NONE leaves, AND joins, no negation: every valid row, see assertNoFilter)
+func (c *SimpleVerifierCircuit) emptyFilter(api frontend.API, h, op int) frontend.Variable {
+	var empty frontend.Variable = 1
+
+	for p := range c.FilterOps[h][op] {
+		empty = api.Mul(empty, lib.IsEqual(api, c.FilterOps[h][op][p], lib.PRED_NONE))
+	}
+
+	for _, join := range c.Joins(h, op) {
+		empty = api.Mul(empty, lib.IsEqual(api, join, lib.JOIN_AND))
+	}
+
+	for node := range c.FilterNots[h][op] {
+		empty = api.Mul(empty, api.IsZero(c.FilterNots[h][op][node]))
+	}
+
+	return empty
+}
+
+// lazySelector builds the lib.ColumnSelector of the given kind on first use,
+// so specialized circuits that never select at a signal index pay nothing
+// (a lookup table without queries is not even created)
+type lazySelector struct {
+	kind  int
+	items [][]frontend.Variable
+	sel   lib.ColumnSelector
+}
+
+// Column implements lib.ColumnSelector
+func (s *lazySelector) Column(api frontend.API, colIndex frontend.Variable) []frontend.Variable {
+	if s.sel == nil {
+		s.sel = lib.NewColumnSelector(api, s.kind, s.items)
+	}
+
+	return s.sel.Column(api, colIndex)
+}
+
+// emptyGroupResult returns an all-zero GROUP BY result (skipped gadget)
+func emptyGroupResult(nGroups int) operators.SumColumnByGroupResult {
+	return operators.SumColumnByGroupResult{
+		GroupSums:   zeros(nGroups),
+		GroupMins:   zeros(nGroups),
+		GroupMaxs:   zeros(nGroups),
+		GroupCounts: zeros(nGroups),
+	}
+}
+
+// boolConst returns the constant 1 if b, else 0
+func boolConst(b bool) frontend.Variable {
+	if b {
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,698 @@
+package circuit_test
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+
+	"simple-verifier-gnark/internal/sample"
+	"simple-verifier-gnark/internal/testutil"
+	"simple-verifier-gnark/pkg/circuit"
+	"simple-verifier-gnark/pkg/lib"
+	"simple-verifier-gnark/pkg/native"
+	"simple-verifier-gnark/pkg/witness"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/constraint/solver"
+	"github.com/consensys/gnark/logger"
+	"github.com/rs/zerolog"
+)
+
+// tinyAssignment returns 3 rows of an 8-bit column and a signed 8-bit column,
+// counted by one handler (the COUNT does not read the cells)
+func tinyAssignment(t *testing.T) *circuit.SimpleVerifierCircuit {
+	t.Helper()
+
+	b := witness.New(sample.Tiny())
+
+	b.SetColumn(0, witness.Column{Bits: 8})
+
+	b.SetColumn(1, witness.Column{Bits: 8, Signed: true})
+
+	b.SetRows(witness.Ints(1, -1), witness.Ints(255, -128), witness.Ints(7, 127))
+
+	b.AddOp(b.AddHandler(0, 2), lib.OP_COUNT)
+
+	assignment, err := b.Assignment(ecc.BN254)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return assignment
+}
+
+// reseal recomputes DataRoot after the cells or NR were changed
+func reseal(t *testing.T, assignment *circuit.SimpleVerifierCircuit) {
+	t.Helper()
+
+	root, err := native.DataRoot(assignment, ecc.BN254)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assignment.DataRoot = root
+}
+
+func TestDataRootBindsData(t *testing.T) {
+	systems := testutil.Compile(t, circuit.New(sample.Tiny()))
+
+	cases := []struct {
+		name   string
+		tamper func(c *circuit.SimpleVerifierCircuit)
+		reseal bool
+		want   bool
+	}{
+		{"honest", func(c *circuit.SimpleVerifierCircuit) {}, false, true},
+		{"tampered cell", func(c *circuit.SimpleVerifierCircuit) {
+			c.Items[0][0] = big.NewInt(2)
+		}, false, false},
+		{"tampered padding cell", func(c *circuit.SimpleVerifierCircuit) {
+			c.Items[1][3] = big.NewInt(1)
+		}, false, false},
+		// COUNT follows NR, so only DataRoot tells the rows apart
+		{"wrong NR", func(c *circuit.SimpleVerifierCircuit) {
+			c.NR = 2
+
+			c.Results[0][0][0] = big.NewInt(2)
+		}, false, false},
+		{"tampered cell with its own root", func(c *circuit.SimpleVerifierCircuit) {
+			c.Items[0][0] = big.NewInt(2)
+		}, true, true},
+		{"wrong NR with its own root", func(c *circuit.SimpleVerifierCircuit) {
+			c.NR = 2
+
+			c.Results[0][0][0] = big.NewInt(2)
+		}, true, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assignment := tinyAssignment(t)
+
+			tc.tamper(assignment)
+
+			if tc.reseal {
+				reseal(t, assignment)
+			}
+
+			testutil.AssertIsSolved(t, circuit.New(sample.Tiny()), assignment, tc.want)
+
+			testutil.AssertSolvedBy(t, systems, assignment, tc.want)
+		})
+	}
+}
+
+func TestCellWidth(t *testing.T) {
+	systems := testutil.Compile(t, circuit.New(sample.Tiny()))
+
+	pow := func(n uint) *big.Int {
+		return new(big.Int).Lsh(big.NewInt(1), n)
+	}
+
+	cases := []struct {
+		name     string
+		col, row int
+		cell     *big.Int
+		want     bool
+	}{
+		{"widest unsigned cell", 0, 0, big.NewInt(255), true},
+		{"unsigned cell over its width", 0, 1, big.NewInt(256), false},
+		{"cell over VALUE_BITS", 0, 1, pow(64), false},
+		{"negative field element", 0, 1, new(big.Int).Sub(ecc.BN254.ScalarField(), big.NewInt(1)), false},
+		{"signed cell over its width", 1, 2, big.NewInt(256), false},
+		{"padded row over its width", 0, 3, pow(70), true},
+		{"padded signed row over its width", 1, 3, pow(9), true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assignment := tinyAssignment(t)
+
+			assignment.Items[tc.col][tc.row] = tc.cell
+
+			// Only the range check can reject the cell
+			reseal(t, assignment)
+
+			testutil.AssertIsSolved(t, circuit.New(sample.Tiny()), assignment, tc.want)
+
+			testutil.AssertSolvedBy(t, systems, assignment, tc.want)
+		})
+	}
+}
+
+// leaf returns the predicate pred on column col with operands a (and b)
+func leaf(pred, col int, args ...int64) *circuit.Filter {
+	f := &circuit.Filter{Pred: pred, Col: col, A: big.NewInt(args[0])}
+
+	if len(args) > 1 {
+		f.B = big.NewInt(args[1])
+	}
+
+	return f
+}
+
+func TestFilterTrees(t *testing.T) {
+	curve := ecc.BN254
+
+	cfg := sample.Shape()
+
+	cfg.MaxPredicates = 4
+
+	const id, a, key, delta = 0, 1, 2, 3
+
+	not := func(f *circuit.Filter) *circuit.Filter { return &circuit.Filter{Not: f} }
+
+	and := func(fs ...*circuit.Filter) *circuit.Filter { return &circuit.Filter{And: fs} }
+
+	or := func(fs ...*circuit.Filter) *circuit.Filter { return &circuit.Filter{Or: fs} }
+
+	// nested selects 7 rows; the forged trees below start from it
+	nested := or(and(leaf(lib.PRED_GE, id, 3), leaf(lib.PRED_LT, id, 8)), not(or(leaf(lib.PRED_EQ, key, 1), leaf(lib.PRED_LT, delta, 0))))
+
+	// keep is the filter over the row (id, a, key, delta) of the sample dataset
+	cases := []struct {
+		name   string
+		filter *circuit.Filter
+		keep   func(row [4]int64) bool
+	}{
+		{"AND", and(leaf(lib.PRED_GE, id, 2), leaf(lib.PRED_EQ, key, 1)), func(r [4]int64) bool {
+			return r[id] >= 2 && r[key] == 1
+		}},
+		{"OR over a signed column", or(leaf(lib.PRED_LT, id, 2), leaf(lib.PRED_LT, delta, -9)), func(r [4]int64) bool {
+			return r[id] < 2 || r[delta] < -9
+		}},
+		{"NOT of a leaf above the bottom level", not(leaf(lib.PRED_EQ, key, 1)), func(r [4]int64) bool {
+			return r[key] != 1
+		}},
+		{"NOT of AND", not(and(leaf(lib.PRED_EQ, key, 0), leaf(lib.PRED_LT, id, 6))), func(r [4]int64) bool {
+			return !(r[key] == 0 && r[id] < 6)
+		}},
+		{"double NOT", not(not(leaf(lib.PRED_RANGE, id, 2, 5))), func(r [4]int64) bool {
+			return r[id] >= 2 && r[id] < 5
+		}},
+		{"AND of OR and NOT", and(or(leaf(lib.PRED_EQ, key, 0), leaf(lib.PRED_EQ, key, 2)), not(leaf(lib.PRED_GE, delta, 0))), func(r [4]int64) bool {
+			return (r[key] == 0 || r[key] == 2) && r[delta] < 0
+		}},
+		{"OR of AND and NOT OR", nested, func(r [4]int64) bool {
+			return (r[id] >= 3 && r[id] < 8) || !(r[key] == 1 || r[delta] < 0)
+		}},
+		{"AND of 3", and(leaf(lib.PRED_GE, id, 1), leaf(lib.PRED_NE, key, 2), leaf(lib.PRED_GT, delta, -10)), func(r [4]int64) bool {
+			return r[id] >= 1 && r[key] != 2 && r[delta] > -10
+		}},
+		{"OR of two ORs", or(or(leaf(lib.PRED_EQ, key, 2), leaf(lib.PRED_EQ, id, 0)), or(leaf(lib.PRED_EQ, delta, -13), leaf(lib.PRED_LE, a, 4))), func(r [4]int64) bool {
+			return r[key] == 2 || r[id] == 0 || r[delta] == -13 || r[a] <= 4
+		}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			b := sample.Dataset(cfg)
+
+			h := b.AddHandler(0, 4)
+
+			b.SetFilter(h, b.AddOp(h, lib.OP_COUNT), tc.filter)
+
+			b.SetFilter(h, b.AddOp(h, lib.OP_SUM_COL, a), tc.filter)
+
+			assignment, err := b.Assignment(curve)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var count, sum int64
+
+			for i := int64(0); i < 10; i++ {
+				if row := [4]int64{i, 3*i + 1, i % 3, 5 - 2*i}; tc.keep(row) {
+					count++
+
+					sum += row[a]
+				}
+			}
+
+			if got := assignment.Results[h][0][0].(*big.Int); got.Cmp(big.NewInt(count)) != 0 {
+				t.Errorf("COUNT = %s, want %d", got, count)
+			}
+
+			if got := assignment.Results[h][1][0].(*big.Int); got.Cmp(big.NewInt(sum)) != 0 {
+				t.Errorf("SUM_COL = %s, want %d", got, sum)
+			}
+
+			testutil.AssertIsSolved(t, circuit.New(cfg), assignment, true)
+		})
+	}
+
+	// Forged trees of nested under its public results
+	forgeries := []struct {
+		name   string
+		tamper func(c *circuit.SimpleVerifierCircuit)
+	}{
+		{"root OR read as AND", func(c *circuit.SimpleVerifierCircuit) {
+			c.FilterJoins[0][0][0] = lib.JOIN_AND
+		}},
+		{"root negated", func(c *circuit.SimpleVerifierCircuit) {
+			c.FilterNots[0][0][0] = 1
+		}},
+		{"unknown join", func(c *circuit.SimpleVerifierCircuit) {
+			c.FilterJoins[0][0][2] = 2
+		}},
+		{"NOT flag not boolean", func(c *circuit.SimpleVerifierCircuit) {
+			c.FilterNots[0][0][2] = 2
+		}},
+		{"NOT of the right subtree dropped", func(c *circuit.SimpleVerifierCircuit) {
+			c.FilterNots[0][0][2] = 0
+		}},
+	}
+
+	for _, tc := range forgeries {
+		t.Run(tc.name, func(t *testing.T) {
+			b := sample.Dataset(cfg)
+
+			h := b.AddHandler(0, 4)
+
+			b.SetFilter(h, b.AddOp(h, lib.OP_COUNT), nested)
+
+			assignment, err := b.Assignment(curve)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tc.tamper(assignment)
+
+			testutil.AssertIsSolved(t, circuit.New(cfg), assignment, false)
+		})
+	}
+
+	// A filter column outside Items would read as all zeros, and key < 5 as 0 < 5 keeps every row
+	for _, col := range []int{cfg.MaxCols, -1} {
+		t.Run(fmt.Sprintf("filter column %d", col), func(t *testing.T) {
+			b := sample.Dataset(cfg)
+
+			h := b.AddHandler(0, 4)
+
+			b.SetFilter(h, b.AddOp(h, lib.OP_COUNT), leaf(lib.PRED_LT, key, 5))
+
+			assignment, err := b.Assignment(curve)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for p, pred := range assignment.FilterOps[h][0] {
+				if pred == lib.PRED_LT {
+					assignment.FilterCols[h][0][p] = col
+				}
+			}
+
+			testutil.AssertIsSolved(t, circuit.New(cfg), assignment, false)
+		})
+	}
+}
+
+// forgeMSB flips the sign bit returned by lib.MSBHint for the cell whose shifted value is shifted
+func forgeMSB(shifted *big.Int) solver.Option {
+	return testutil.ForgeHint(lib.MSBHint, func(field *big.Int, inputs, outputs []*big.Int) {
+		if inputs[0].Cmp(shifted) == 0 {
+			outputs[0].Sub(big.NewInt(1), outputs[0])
+		}
+	})
+}
+
+func TestSingleLeafFilters(t *testing.T) {
+	cfg := sample.Tiny()
+
+	c := circuit.New(cfg)
+
+	if c.FilterJoins != nil || c.Joins(0, 0) != nil {
+		t.Fatalf("MaxPredicates = 1 allocates joins: %v", c.FilterJoins)
+	}
+
+	// gnark warns on each zero-length public slice when it compiles or builds a witness
+	var log bytes.Buffer
+
+	logger.Set(zerolog.New(&log))
+
+	defer logger.Disable()
+
+	systems := testutil.Compile(t, c)
+
+	b := witness.New(cfg)
+
+	b.SetRows(witness.Ints(1, 10), witness.Ints(2, 20), witness.Ints(3, 30))
+
+	h := b.AddHandler(0, 2)
+
+	b.SetFilter(h, b.AddOp(h, lib.OP_SUM_COL, 1), &circuit.Filter{Pred: lib.PRED_GE, Col: 0, A: big.NewInt(2)})
+
+	assignment, err := b.Assignment(ecc.BN254)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := assignment.Results[h][0][0].(*big.Int); got.Int64() != 50 {
+		t.Fatalf("SUM_COL WHERE id >= 2 = %s, want 50", got)
+	}
+
+	testutil.AssertSolvedBy(t, systems, assignment, true)
+
+	if strings.Contains(log.String(), "uninitialized slice") {
+		t.Errorf("gnark warnings:\n%s", log.String())
+	}
+}
+
+func TestSignedDecodingRejectsForgedMSB(t *testing.T) {
+	systems := testutil.Compile(t, circuit.New(sample.Tiny()))
+
+	// 8-bit cells are shifted by 2^56 so their top bit lands on bit 63
+	shift := func(cell int64) *big.Int {
+		return new(big.Int).Lsh(big.NewInt(cell), 56)
+	}
+
+	// No cell matches: the override alone changes nothing
+	testutil.AssertSolvedBy(t, systems, tinyAssignment(t), true, forgeMSB(big.NewInt(-1)))
+
+	cases := []struct {
+		name string
+		cell int64
+	}{
+		{"negative signed cell read as positive", 128},
+		{"positive signed cell read as negative", 127},
+		{"unsigned cell with a sign bit", 7},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			testutil.AssertSolvedBy(t, systems, tinyAssignment(t), false, forgeMSB(shift(tc.cell)))
+		})
+	}
+}
+
+func TestSignedComparisons(t *testing.T) {
+	curve := ecc.BN254
+
+	field := curve.ScalarField()
+
+	fe := func(v int64) *big.Int {
+		return new(big.Int).Mod(big.NewInt(v), field)
+	}
+
+	// delta (column 3) runs from 5 down to -13 in steps of 2
+	b := sample.Dataset(sample.Shape())
+
+	h := b.AddHandler(0, 4)
+
+	minOp := b.AddOp(h, lib.OP_MIN_COL, 3)
+
+	b.SetFilter(h, minOp, &circuit.Filter{Pred: lib.PRED_GT, Col: 3, A: big.NewInt(-4)})
+
+	maxOp := b.AddOp(h, lib.OP_MAX_COL, 3)
+
+	b.SetFilter(h, maxOp, &circuit.Filter{Pred: lib.PRED_LT, Col: 3, A: big.NewInt(2)})
+
+	countOp := b.AddOp(h, lib.OP_COUNT)
+
+	b.SetFilter(h, countOp, &circuit.Filter{Pred: lib.PRED_RANGE, Col: 3, A: big.NewInt(-7), B: big.NewInt(2)})
+
+	sumOp := b.AddOp(h, lib.OP_SUM_COL, 3)
+
+	b.SetFilter(h, sumOp, &circuit.Filter{Pred: lib.PRED_GE, Col: 3, A: big.NewInt(-1)})
+
+	assignment, err := b.Assignment(curve)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Each forged value reads delta in the unsigned order of the field: MIN / MAX
+	// over the same rows, COUNT / SUM over the rows an unsigned filter selects
+	cases := []struct {
+		name          string
+		op            int
+		value, forged *big.Int
+	}{
+		{"MIN_COL WHERE delta > -4", minOp, fe(-3), fe(1)},
+		{"MAX_COL WHERE delta < 2", maxOp, fe(1), fe(-1)},
+		{"COUNT WHERE delta in [-7, 2)", countOp, fe(5), fe(0)},
+		{"SUM_COL WHERE delta >= -1", sumOp, fe(8), fe(-1)},
+	}
+
+	for _, tc := range cases {
+		if got := assignment.Results[h][tc.op][0].(*big.Int); got.Cmp(tc.value) != 0 {
+			t.Errorf("%s = %s, want %s", tc.name, got, tc.value)
+		}
+	}
+
+	testutil.AssertIsSolved(t, circuit.New(sample.Shape()), assignment, true)
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assignment.Results[h][tc.op][0] = tc.forged
+
+			testutil.AssertIsSolved(t, circuit.New(sample.Shape()), assignment, false)
+
+			assignment.Results[h][tc.op][0] = tc.value
+		})
+	}
+}
+
+func TestSelectors(t *testing.T) {
+	curve := ecc.BN254
+
+	for _, kind := range []int{lib.SELECTOR_MUX, lib.SELECTOR_ONE_HOT, lib.SELECTOR_LOOKUP} {
+		cfg := sample.Shape()
+
+		cfg.Selector = kind
+
+		t.Run(cfg.Name(), func(t *testing.T) {
+			b := sample.Dataset(cfg)
+
+			h := b.AddHandler(0, 4)
+
+			sum := b.AddOp(h, lib.OP_SUM_COL, 1)
+
+			b.AddOp(h, lib.OP_MIN_COL, 3)
+
+			b.AddOp(h, lib.OP_SUM_PRODUCT, 1, 3)
+
+			b.SetGroups(h, b.AddOp(h, lib.OP_SUM_COL_BY, 3, 2), witness.Ints(0, 1, 2)...)
+
+			assignment, err := b.Assignment(curve)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			testutil.AssertIsSolved(t, circuit.New(cfg), assignment, true)
+
+			// Args of inactive handlers are not checked
+			assignment.OpArgs[2][0][0] = 100
+
+			testutil.AssertIsSolved(t, circuit.New(cfg), assignment, true)
+
+			cases := []struct {
+				name   string
+				arg    int
+				col    any
+				result int64
+			}{
+				{"other column", 0, 0, 145},
+				// Every selector reads an all-zero column at MaxCols, matching a zero sum
+				{"column out of range", 0, cfg.MaxCols, 0},
+				{"unused arg out of range", 1, cfg.MaxCols, 145},
+				// p - 1: LessThan alone reads it as below MaxCols
+				{"negative column", 0, -1, 0},
+				{"very large column", 0, new(big.Int).Lsh(big.NewInt(1), 200), 0},
+			}
+
+			for _, tc := range cases {
+				t.Run(tc.name, func(t *testing.T) {
+					arg, result := assignment.OpArgs[h][sum][tc.arg], assignment.Results[h][sum][0]
+
+					assignment.OpArgs[h][sum][tc.arg], assignment.Results[h][sum][0] = tc.col, tc.result
+
+					testutil.AssertIsSolved(t, circuit.New(cfg), assignment, false)
+
+					assignment.OpArgs[h][sum][tc.arg], assignment.Results[h][sum][0] = arg, result
+				})
+			}
+		})
+	}
+}
+
+func TestMerkle16OpsShareHandler(t *testing.T) {
+	b := sample.Dataset(sample.Shape())
+
+	h := b.AddHandler(1, 2)
+
+	b.AddOp(h, lib.OP_MERKLE16)
+
+	b.AddOp(h, lib.OP_MERKLE16)
+
+	assignment, err := b.Assignment(ecc.BN254)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testutil.AssertIsSolved(t, circuit.New(sample.Shape()), assignment, true)
+
+	// Opcodes of the inactive handler are not checked
+	assignment.OpCodes[1][0], assignment.OpCodes[1][1] = lib.OP_MERKLE16, lib.OP_MERKLE16
+
+	testutil.AssertIsSolved(t, circuit.New(sample.Shape()), assignment, true)
+
+	assignment.Results[0][1][0] = 1
+
+	testutil.AssertIsSolved(t, circuit.New(sample.Shape()), assignment, false)
+}
+
+func TestFilteredMerkle16Plan(t *testing.T) {
+	curve := ecc.BN254
+
+	plan := circuit.Plan{Handlers: [][]circuit.PlanOp{{
+		{OpCode: lib.OP_MERKLE16, Filtered: true},
+		{OpCode: lib.OP_MERKLE16},
+	}}}
+
+	b := sample.Dataset(sample.Shape())
+
+	h := b.AddHandler(0, 4)
+
+	b.AddOp(h, lib.OP_MERKLE16)
+
+	b.AddOp(h, lib.OP_MERKLE16)
+
+	assignment, err := b.Assignment(curve)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := assignment.SetFilter(0, 0, &circuit.Filter{Pred: lib.PRED_LT, Col: 0, A: big.NewInt(5)}); err != nil {
+		t.Fatal(err)
+	}
+
+	assignment.Plan = &plan
+
+	if err := native.Evaluate(assignment, curve); err != nil {
+		t.Fatal(err)
+	}
+
+	filtered, all := assignment.Results[0][0][0], assignment.Results[0][1][0]
+
+	if filtered.(*big.Int).Cmp(all.(*big.Int)) == 0 {
+		t.Fatal("filtered and unfiltered MERKLE16 roots are equal")
+	}
+
+	testutil.AssertIsSolved(t, circuit.NewSpecialized(sample.Shape(), plan), assignment, true)
+
+	assignment.Results[0][0][0], assignment.Results[0][1][0] = all, filtered
+
+	testutil.AssertIsSolved(t, circuit.NewSpecialized(sample.Shape(), plan), assignment, false)
+}
+
+func TestFilteredMerkle16(t *testing.T) {
+	curve := ecc.BN254
+
+	b := sample.Dataset(sample.Shape())
+
+	h := b.AddHandler(0, 4)
+
+	b.AddOp(h, lib.OP_MERKLE16)
+
+	b.AddOp(h, lib.OP_MERKLE16)
+
+	b.SetFilter(h, 1, &circuit.Filter{Pred: lib.PRED_GE, Col: 2, A: big.NewInt(1)})
+
+	assignment, err := b.Assignment(curve)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	all, filtered := assignment.Results[0][0][0], assignment.Results[0][1][0]
+
+	if filtered.(*big.Int).Cmp(all.(*big.Int)) == 0 {
+		t.Fatal("filtered and unfiltered MERKLE16 roots are equal")
+	}
+
+	testutil.AssertIsSolved(t, circuit.New(sample.Shape()), assignment, true)
+
+	assignment.Results[0][0][0], assignment.Results[0][1][0] = filtered, all
+
+	testutil.AssertIsSolved(t, circuit.New(sample.Shape()), assignment, false)
+}
+
+func TestFilteredMerkle16Capacity(t *testing.T) {
+	curve := ecc.BN254
+
+	// Two filtered MERKLE16 ops need two filtered trees
+	wide := sample.Shape()
+
+	wide.MaxMerkleFilters = 2
+
+	for _, cfg := range []circuit.Config{sample.Shape(), wide} {
+		b := sample.Dataset(cfg)
+
+		h := b.AddHandler(0, 4)
+
+		b.AddOp(h, lib.OP_MERKLE16)
+
+		b.AddOp(h, lib.OP_MERKLE16)
+
+		b.SetFilter(h, 0, &circuit.Filter{Pred: lib.PRED_LT, Col: 0, A: big.NewInt(5)})
+
+		b.SetFilter(h, 1, &circuit.Filter{Pred: lib.PRED_GE, Col: 2, A: big.NewInt(1)})
+
+		assignment, err := b.Assignment(curve)
+
+		if cfg == sample.Shape() {
+			if err == nil {
+				t.Fatal("two filtered MERKLE16 ops accepted with MaxMerkleFilters = 1")
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		testutil.AssertIsSolved(t, circuit.New(wide), assignment, true)
+
+		// Results evaluated for two trees, checked against one
+		testutil.AssertIsSolved(t, circuit.New(sample.Shape()), assignment, false)
+	}
+
+	// A second filter set after evaluation: the op keeps the unfiltered root,
+	// so only the filtered tree count rejects it
+	b := sample.Dataset(sample.Shape())
+
+	h := b.AddHandler(0, 4)
+
+	b.AddOp(h, lib.OP_MERKLE16)
+
+	b.AddOp(h, lib.OP_MERKLE16)
+
+	b.SetFilter(h, 0, &circuit.Filter{Pred: lib.PRED_LT, Col: 0, A: big.NewInt(5)})
+
+	assignment, err := b.Assignment(curve)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testutil.AssertIsSolved(t, circuit.New(sample.Shape()), assignment, true)
+
+	if err := assignment.SetFilter(0, 1, &circuit.Filter{Pred: lib.PRED_GE, Col: 0, A: big.NewInt(0)}); err != nil {
+		t.Fatal(err)
+	}
+
+	testutil.AssertIsSolved(t, circuit.New(sample.Shape()), assignment, false)
+}
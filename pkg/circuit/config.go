@@ -0,0 +1,109 @@
+package circuit
+
+import (
+	"fmt"
+
+	"simple-verifier-gnark/pkg/lib"
+)
+
+// Config holds the dimensions of a SimpleVerifierCircuit
+// Every public and private input is allocated from these values by New
+type Config struct {
+	// MaxRows: rows of the Items matrix (NR <= MaxRows)
+	MaxRows int
+
+	// MaxCols: columns of the Items matrix
+	MaxCols int
+
+	// MaxGroups: group keys / result slots per op
+	MaxGroups int
+
+	// MaxOps: op slots per handler
+	MaxOps int
+
+	// MaxHandlers: handler slots
+	MaxHandlers int
+
+	// MaxPredicates: filter tree leaves per op (power of 2)
+	MaxPredicates int
+
+	// MaxMerkleFilters: MERKLE16 ops with a non-empty filter, over all handlers
+	// Each one hashes a tree of its own rows; unfiltered MERKLE16 ops share one tree
+	MaxMerkleFilters int
+
+	// Selector: column extraction strategy (lib.SELECTOR_* constants)
+	// Does not change the inputs, only the constraints
+	Selector int
+}
+
+// DefaultConfig returns the 256x16 shape of the lib constants
+// Matches the circom circuit: SimpleVerifier(256, 16, 32, 4, 4)
+func DefaultConfig() Config {
+	return Config{
+		MaxRows:          lib.MAX_ROWS,
+		MaxCols:          lib.MAX_COLS,
+		MaxGroups:        lib.MAX_GROUPS,
+		MaxOps:           lib.MAX_OPS,
+		MaxHandlers:      lib.MAX_HANDLERS,
+		MaxPredicates:    lib.MAX_PREDICATES,
+		MaxMerkleFilters: lib.MAX_MERKLE_FILTERS,
+		Selector:         lib.SELECTOR_MUX,
+	}
+}
+
+// TotalItems returns the number of cells of the Items matrix
+func (cfg Config) TotalItems() int {
+	return cfg.MaxRows * cfg.MaxCols
+}
+
+// NLevels returns the 16-ary Merkle depth covering all cells
+// 256x16 = 4096 cells => 3 levels, 64x4 = 256 cells => 2 levels
+func (cfg Config) NLevels() int {
+	return lib.MerkleLevels(cfg.TotalItems())
+}
+
+// Validate checks that the shape can be compiled
+func (cfg Config) Validate() error {
+	if cfg.MaxRows < 1 || cfg.MaxRows >= 1<<lib.COUNT_BITS {
+		return fmt.Errorf("circuit config: MaxRows must be in [1, 2^%d), got %d", lib.COUNT_BITS, cfg.MaxRows)
+	}
+
+	if cfg.MaxCols < 1 {
+		return fmt.Errorf("circuit config: MaxCols must be >= 1, got %d", cfg.MaxCols)
+	}
+
+	// Slot 1 carries the AVG / SUM_PRODUCT remainder
+	if cfg.MaxGroups < 2 {
+		return fmt.Errorf("circuit config: MaxGroups must be >= 2, got %d", cfg.MaxGroups)
+	}
+
+	if cfg.MaxOps < 1 {
+		return fmt.Errorf("circuit config: MaxOps must be >= 1, got %d", cfg.MaxOps)
+	}
+
+	if cfg.MaxHandlers < 1 {
+		return fmt.Errorf("circuit config: MaxHandlers must be >= 1, got %d", cfg.MaxHandlers)
+	}
+
+	if cfg.MaxPredicates < 1 || cfg.MaxPredicates&(cfg.MaxPredicates-1) != 0 {
+		return fmt.Errorf("circuit config: MaxPredicates must be a power of 2, got %d", cfg.MaxPredicates)
+	}
+
+	if cfg.MaxMerkleFilters < 0 {
+		return fmt.Errorf("circuit config: MaxMerkleFilters must be >= 0, got %d", cfg.MaxMerkleFilters)
+	}
+
+	switch cfg.Selector {
+	case lib.SELECTOR_MUX, lib.SELECTOR_ONE_HOT, lib.SELECTOR_LOOKUP:
+	default:
+		return fmt.Errorf("circuit config: unknown Selector %d", cfg.Selector)
+	}
+
+	return nil
+}
+
+// String returns the shape as rows x cols (groups/ops/handlers/predicates/merkle filters)
+func (cfg Config) String() string {
+	return fmt.Sprintf("%dx%d (groups=%d, ops=%d, handlers=%d, predicates=%d, merkle filters=%d)",
+		cfg.MaxRows, cfg.MaxCols, cfg.MaxGroups, cfg.MaxOps, cfg.MaxHandlers, cfg.MaxPredicates, cfg.MaxMerkleFilters)
+}
@@ -0,0 +1,56 @@
+package circuit
+
+import (
+	"strings"
+	"testing"
+
+	"simple-verifier-gnark/pkg/lib"
+)
+
+func TestConfigValidate(t *testing.T) {
+	if err := DefaultConfig().Validate(); err != nil {
+		t.Fatalf("default config: %v", err)
+	}
+
+	cases := []struct {
+		name   string
+		modify func(cfg *Config)
+		want   string
+	}{
+		{"no rows", func(cfg *Config) { cfg.MaxRows = 0 }, "MaxRows must be in [1, 2^16), got 0"},
+		{"rows at 2^16", func(cfg *Config) { cfg.MaxRows = 1 << 16 }, "MaxRows must be in [1, 2^16), got 65536"},
+		{"no columns", func(cfg *Config) { cfg.MaxCols = 0 }, "MaxCols must be >= 1"},
+		{"one group", func(cfg *Config) { cfg.MaxGroups = 1 }, "MaxGroups must be >= 2, got 1"},
+		{"no ops", func(cfg *Config) { cfg.MaxOps = 0 }, "MaxOps must be >= 1"},
+		{"no handlers", func(cfg *Config) { cfg.MaxHandlers = 0 }, "MaxHandlers must be >= 1"},
+		{"no predicates", func(cfg *Config) { cfg.MaxPredicates = 0 }, "MaxPredicates must be a power of 2, got 0"},
+		{"3 predicates", func(cfg *Config) { cfg.MaxPredicates = 3 }, "MaxPredicates must be a power of 2, got 3"},
+		{"6 predicates", func(cfg *Config) { cfg.MaxPredicates = 6 }, "MaxPredicates must be a power of 2, got 6"},
+		{"negative merkle filters", func(cfg *Config) { cfg.MaxMerkleFilters = -1 }, "MaxMerkleFilters must be >= 0"},
+		{"unknown selector", func(cfg *Config) { cfg.Selector = 7 }, "unknown Selector 7"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+
+			tc.modify(&cfg)
+
+			err := cfg.Validate()
+
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("error %v, want %q", err, tc.want)
+			}
+		})
+	}
+
+	// Boundaries that stay valid
+	for _, cfg := range []Config{
+		{MaxRows: 1<<16 - 1, MaxCols: 1, MaxGroups: 2, MaxOps: 1, MaxHandlers: 1, MaxPredicates: 1},
+		{MaxRows: 1, MaxCols: 1, MaxGroups: 2, MaxOps: 1, MaxHandlers: 1, MaxPredicates: 8, Selector: lib.SELECTOR_LOOKUP},
+	} {
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("%s: %v", cfg, err)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package circuit
+
+import (
+	"fmt"
+	"math/big"
+
+	"simple-verifier-gnark/pkg/lib"
+
+	"github.com/consensys/gnark/frontend"
+)
+
+// Filter is a row predicate expression (WHERE clause) of one op
+// Exactly one of And, Or, Not or the leaf predicate is set; see SetFilter
+type Filter struct {
+	And []*Filter
+	Or  []*Filter
+	Not *Filter
+
+	// Leaf: Pred (lib.PRED_*) on column Col with operands A, B (signed, nil = 0)
+	Pred int
+	Col  int
+	A    *big.Int
+	B    *big.Int
+}
+
+// Leaves returns the predicate tree leaves needed by f (a power of 2, see Config.MaxPredicates)
+// And / Or of n sub-filters are nested pairwise: a AND (b AND c)
+func (f *Filter) Leaves() int {
+	return 1 << f.height()
+}
+
+// height returns the depth of the binary tree f is placed in
+func (f *Filter) height() int {
+	if f.Not != nil {
+		return f.Not.height()
+	}
+
+	children := f.children()
+
+	switch len(children) {
+	case 0:
+		return 0
+	case 1:
+		return children[0].height()
+	}
+
+	left, right := children[0].height(), f.rest().height()
+
+	if right > left {
+		left = right
+	}
+
+	return left + 1
+}
+
+// children returns the sub-filters of an And / Or node (nil for leaves)
+func (f *Filter) children() []*Filter {
+	if f.Or != nil {
+		return f.Or
+	}
+
+	return f.And
+}
+
+// rest returns the node joining every child but the first (f must have 2+ children)
+func (f *Filter) rest() *Filter {
+	children := f.children()
+
+	if len(children) == 2 {
+		return children[1]
+	}
+
+	if f.Or != nil {
+		return &Filter{Or: children[1:]}
+	}
+
+	return &Filter{And: children[1:]}
+}
+
+// SetFilter assigns the predicate tree of op slot [h][op] from f (nil = every row)
+//
+// The slot is reset to the empty tree first (PRED_NONE leaves, AND joins, no NOT).
+// A subtree shorter than its position is padded with PRED_NONE (true) leaves on
+// the right of an AND node; NOT is applied to the lowest node of a chain.
+func (c *SimpleVerifierCircuit) SetFilter(h, op int, f *Filter) error {
+	cfg := c.Config
+
+	for p := 0; p < cfg.MaxPredicates; p++ {
+		c.FilterOps[h][op][p] = lib.PRED_NONE
+
+		c.FilterCols[h][op][p] = 0
+
+		c.FilterArgs[h][op][p] = [2]frontend.Variable{0, 0}
+	}
+
+	for node := 0; node < cfg.MaxPredicates-1; node++ {
+		c.FilterJoins[h][op][node] = lib.JOIN_AND
+	}
+
+	for node := 0; node < 2*cfg.MaxPredicates-1; node++ {
+		c.FilterNots[h][op][node] = 0
+	}
+
+	if f == nil {
+		return nil
+	}
+
+	if f.Leaves() > cfg.MaxPredicates {
+		return fmt.Errorf("filter: handler %d op %d needs %d predicates, shape allows %d", h, op, f.Leaves(), cfg.MaxPredicates)
+	}
+
+	depth := 0
+
+	for 1<<depth < cfg.MaxPredicates {
+		depth++
+	}
+
+	return c.placeFilter(h, op, f, 0, depth, false)
+}
+
+// placeFilter writes f at tree node (heap order) of the given height, negated if neg
+func (c *SimpleVerifierCircuit) placeFilter(h, op int, f *Filter, node, height int, neg bool) error {
+	if f.Not != nil {
+		return c.placeFilter(h, op, f.Not, node, height, !neg)
+	}
+
+	children := f.children()
+
+	if (f.And != nil || f.Or != nil) && len(children) == 0 {
+		return fmt.Errorf("filter: handler %d op %d: empty and / or", h, op)
+	}
+
+	if len(children) == 1 {
+		return c.placeFilter(h, op, children[0], node, height, neg)
+	}
+
+	if len(children) > 1 {
+		c.FilterJoins[h][op][node] = lib.JOIN_AND
+
+		if f.Or != nil {
+			c.FilterJoins[h][op][node] = lib.JOIN_OR
+		}
+
+		c.FilterNots[h][op][node] = boolConst(neg)
+
+		if err := c.placeFilter(h, op, children[0], 2*node+1, height-1, false); err != nil {
+			return err
+		}
+
+		return c.placeFilter(h, op, f.rest(), 2*node+2, height-1, false)
+	}
+
+	// Leaf above the bottom level: AND with an all-true right subtree
+	if height > 0 {
+		c.FilterJoins[h][op][node] = lib.JOIN_AND
+
+		return c.placeFilter(h, op, f, 2*node+1, height-1, neg)
+	}
+
+	if f.Pred < lib.PRED_NONE || f.Pred > lib.PRED_RANGE {
+		return fmt.Errorf("filter: handler %d op %d: unknown predicate %d", h, op, f.Pred)
+	}
+
+	if f.Col < 0 || f.Col >= c.Config.MaxCols {
+		return fmt.Errorf("filter: handler %d op %d: column %d out of range", h, op, f.Col)
+	}
+
+	leaf := node - (c.Config.MaxPredicates - 1)
+
+	c.FilterOps[h][op][leaf] = f.Pred
+
+	c.FilterCols[h][op][leaf] = f.Col
+
+	c.FilterArgs[h][op][leaf] = [2]frontend.Variable{orZero(f.A), orZero(f.B)}
+
+	c.FilterNots[h][op][node] = boolConst(neg)
+
+	return nil
+}
+
+// orZero returns a copy of v, or 0 if v is nil
+func orZero(v *big.Int) *big.Int {
+	if v == nil {
+		return big.NewInt(0)
+	}
+
+	return new(big.Int).Set(v)
+}
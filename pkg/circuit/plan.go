@@ -0,0 +1,170 @@
+package circuit
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"simple-verifier-gnark/pkg/lib"
+
+	"github.com/consensys/gnark/frontend"
+)
+
+// PlanOp is one op slot of a fixed query plan
+// OpCode and the column args are compile-time constants of the circuit
+type PlanOp struct {
+	OpCode int
+
+	// ColX / ColY: OpArgs[h][op][0] / OpArgs[h][op][1]
+	ColX int
+	ColY int
+
+	// Filtered: keep the public predicate tree for this op
+	// When false the op runs on the shared row mask and its filter inputs must be empty
+	Filtered bool
+}
+
+// Plan is a fixed query plan [handler][op]
+// Handlers and ops beyond the plan are OP_NOOP; NumHandlers must equal len(Handlers)
+type Plan struct {
+	Handlers [][]PlanOp
+}
+
+// NewSpecialized allocates a circuit that only contains the gadgets needed by plan
+// Public inputs keep the layout of New(cfg), so the same assignment can be used;
+// OpCodes, OpArgs and NumHandlers are asserted equal to the plan constants.
+func NewSpecialized(cfg Config, plan Plan) *SimpleVerifierCircuit {
+	c := New(cfg)
+
+	c.Plan = &plan
+
+	return c
+}
+
+// Validate checks that the plan fits cfg and only uses known opcodes
+func (p *Plan) Validate(cfg Config) error {
+	if len(p.Handlers) > cfg.MaxHandlers {
+		return fmt.Errorf("query plan: %d handlers, shape allows %d", len(p.Handlers), cfg.MaxHandlers)
+	}
+
+	merkleFilters := 0
+
+	for h, ops := range p.Handlers {
+		if len(ops) > cfg.MaxOps {
+			return fmt.Errorf("query plan: handler %d has %d ops, shape allows %d", h, len(ops), cfg.MaxOps)
+		}
+
+		for op, planOp := range ops {
+			if !isKnownOpCode(planOp.OpCode) {
+				return fmt.Errorf("query plan: handler %d op %d: unknown opcode %d", h, op, planOp.OpCode)
+			}
+
+			if planOp.ColX < 0 || planOp.ColX >= cfg.MaxCols || planOp.ColY < 0 || planOp.ColY >= cfg.MaxCols {
+				return fmt.Errorf("query plan: handler %d op %d: column args (%d, %d) out of range", h, op, planOp.ColX, planOp.ColY)
+			}
+
+			if planOp.OpCode == lib.OP_MERKLE16 && planOp.Filtered {
+				merkleFilters++
+			}
+		}
+	}
+
+	if merkleFilters > cfg.MaxMerkleFilters {
+		return fmt.Errorf("query plan: %d filtered MERKLE16 ops, shape allows %d", merkleFilters, cfg.MaxMerkleFilters)
+	}
+
+	return nil
+}
+
+// Hash returns an identifier of the plan (opcodes, args and filter flags)
+// It names the key cache of specialized circuits, so it must be collision
+// resistant: sha256 truncated to 16 bytes (32 hex digits).
+func (p *Plan) Hash() string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%v", p.Handlers)))
+
+	return fmt.Sprintf("%x", sum[:16])
+}
+
+// op returns the plan op of a slot (OP_NOOP outside the plan)
+func (p *Plan) op(h, op int) PlanOp {
+	if h >= len(p.Handlers) || op >= len(p.Handlers[h]) {
+		return PlanOp{OpCode: lib.OP_NOOP}
+	}
+
+	return p.Handlers[h][op]
+}
+
+// uses reports whether the slot may run one of codes
+// Without a plan (nil) every slot may run every opcode
+func (p *Plan) uses(h, op int, codes ...int) bool {
+	if p == nil {
+		return true
+	}
+
+	opCode := p.op(h, op).OpCode
+
+	for _, code := range codes {
+		if opCode == code {
+			return true
+		}
+	}
+
+	return false
+}
+
+// filtered reports whether the slot evaluates its public predicate tree
+func (p *Plan) filtered(h, op int) bool {
+	if p == nil {
+		return true
+	}
+
+	planOp := p.op(h, op)
+
+	return planOp.OpCode != lib.OP_NOOP && planOp.Filtered
+}
+
+// constFlags returns the opcode selectors of a plan op as constants
+func constFlags(opCode int) opFlags {
+	flag := func(code int) frontend.Variable {
+		if opCode == code {
+			return 1
+		}
+
+		return 0
+	}
+
+	return opFlags{
+		isNoop:    flag(lib.OP_NOOP),
+		isMerkle:  flag(lib.OP_MERKLE16),
+		isCount:   flag(lib.OP_COUNT),
+		isSum:     flag(lib.OP_SUM_COL),
+		isMin:     flag(lib.OP_MIN_COL),
+		isMax:     flag(lib.OP_MAX_COL),
+		isAvg:     flag(lib.OP_AVG_COL),
+		isSumProd: flag(lib.OP_SUM_PRODUCT),
+		isSumBy:   flag(lib.OP_SUM_COL_BY),
+		isMinBy:   flag(lib.OP_MIN_COL_BY),
+		isMaxBy:   flag(lib.OP_MAX_COL_BY),
+		isCountBy: flag(lib.OP_COUNT_BY),
+	}
+}
+
+// isKnownOpCode reports whether opCode is one of the lib opcodes
+func isKnownOpCode(opCode int) bool {
+	switch opCode {
+	case lib.OP_NOOP,
+		lib.OP_MERKLE16,
+		lib.OP_COUNT,
+		lib.OP_SUM_COL,
+		lib.OP_MIN_COL,
+		lib.OP_MAX_COL,
+		lib.OP_AVG_COL,
+		lib.OP_SUM_PRODUCT,
+		lib.OP_SUM_COL_BY,
+		lib.OP_MIN_COL_BY,
+		lib.OP_MAX_COL_BY,
+		lib.OP_COUNT_BY:
+		return true
+	}
+
+	return false
+}
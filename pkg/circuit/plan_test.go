@@ -0,0 +1,40 @@
+package circuit
+
+import (
+	"testing"
+
+	"simple-verifier-gnark/pkg/lib"
+)
+
+func TestPlanHash(t *testing.T) {
+	plans := []Plan{
+		{},
+		{Handlers: [][]PlanOp{{{OpCode: lib.OP_SUM_COL, ColX: 1}}}},
+		{Handlers: [][]PlanOp{{{OpCode: lib.OP_SUM_COL, ColX: 2}}}},
+		{Handlers: [][]PlanOp{{{OpCode: lib.OP_SUM_COL, ColX: 1, Filtered: true}}}},
+		{Handlers: [][]PlanOp{{{OpCode: lib.OP_SUM_COL, ColX: 1}}, {}}},
+		{Handlers: [][]PlanOp{{{OpCode: lib.OP_MERKLE16}, {OpCode: lib.OP_COUNT}}}},
+	}
+
+	seen := make(map[string]int)
+
+	for i := range plans {
+		hash := plans[i].Hash()
+
+		if len(hash) != 32 {
+			t.Errorf("plan %d: hash %q is not 32 hex digits", i, hash)
+		}
+
+		if j, ok := seen[hash]; ok {
+			t.Errorf("plans %d and %d share hash %s", j, i, hash)
+		}
+
+		seen[hash] = i
+
+		same := Plan{Handlers: append([][]PlanOp(nil), plans[i].Handlers...)}
+
+		if same.Hash() != hash {
+			t.Errorf("plan %d: equal plans hash differently", i)
+		}
+	}
+}
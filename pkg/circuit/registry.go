@@ -0,0 +1,149 @@
+package circuit
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"simple-verifier-gnark/pkg/lib"
+)
+
+// Requirements describes the smallest shape a job needs
+// Rows/Cols are the dataset dimensions, the rest come from the query
+type Requirements struct {
+	Rows       int
+	Cols       int
+	Groups     int
+	Ops        int
+	Handlers   int
+	Predicates int
+
+	// MerkleFilters counts the MERKLE16 ops with a non-empty filter
+	MerkleFilters int
+}
+
+// shapes is the registry of pre-compiled circuit shapes, smallest first
+// Keys for each shape are cached by name (see keystore.Load)
+// shapesMu guards it: RegisterShape may run concurrently with SelectShape.
+var shapes = []Config{
+	{MaxRows: 64, MaxCols: 4, MaxGroups: 8, MaxOps: 2, MaxHandlers: 1, MaxPredicates: 2, MaxMerkleFilters: 1},
+	{MaxRows: 64, MaxCols: 8, MaxGroups: 8, MaxOps: 4, MaxHandlers: 4, MaxPredicates: 4, MaxMerkleFilters: 1},
+	DefaultConfig(),
+	{MaxRows: 4096, MaxCols: 8, MaxGroups: 4096, MaxOps: 2, MaxHandlers: 2, MaxPredicates: 2, MaxMerkleFilters: 1},
+	{MaxRows: 1024, MaxCols: 32, MaxGroups: 64, MaxOps: 4, MaxHandlers: 4, MaxPredicates: 4, MaxMerkleFilters: 1},
+}
+
+var shapesMu sync.RWMutex
+
+// Shapes returns a copy of the registered shapes, sorted by Cost
+func Shapes() []Config {
+	shapesMu.RLock()
+
+	defer shapesMu.RUnlock()
+
+	return append([]Config(nil), shapes...)
+}
+
+// RegisterShape adds a shape to the registry (kept sorted by Cost)
+// Safe for concurrent use with Shapes and SelectShape
+func RegisterShape(cfg Config) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	shapesMu.Lock()
+
+	defer shapesMu.Unlock()
+
+	for _, shape := range shapes {
+		if shape == cfg {
+			return nil
+		}
+	}
+
+	shapes = append(shapes, cfg)
+
+	sort.SliceStable(shapes, func(i, j int) bool {
+		return shapes[i].Cost() < shapes[j].Cost()
+	})
+
+	return nil
+}
+
+// SelectShape returns the cheapest registered shape that fits req
+func SelectShape(req Requirements) (Config, error) {
+	found := false
+
+	var best Config
+
+	for _, shape := range Shapes() {
+		if !shape.Fits(req) {
+			continue
+		}
+
+		if !found || shape.Cost() < best.Cost() {
+			best = shape
+
+			found = true
+		}
+	}
+
+	if !found {
+		return Config{}, fmt.Errorf("circuit registry: no shape fits %d rows x %d cols (groups=%d, ops=%d, handlers=%d, predicates=%d, merkle filters=%d)",
+			req.Rows, req.Cols, req.Groups, req.Ops, req.Handlers, req.Predicates, req.MerkleFilters)
+	}
+
+	return best, nil
+}
+
+// Fits reports whether a job with requirements req can run on this shape
+func (cfg Config) Fits(req Requirements) bool {
+	return req.Rows <= cfg.MaxRows &&
+		req.Cols <= cfg.MaxCols &&
+		req.Groups <= cfg.MaxGroups &&
+		req.Ops <= cfg.MaxOps &&
+		req.Handlers <= cfg.MaxHandlers &&
+		req.Predicates <= cfg.MaxPredicates &&
+		req.MerkleFilters <= cfg.MaxMerkleFilters
+}
+
+// Cost is a rough, relative estimate of the constraint count of a shape
+// Data commitment and decoding scale with rows*cols; every op slot selects
+// columns (cols) for its filters (predicates) per row, and groups rows
+// with a log-derivative argument (rows + groups). Each filtered MERKLE16
+// slot hashes one more tree of rows*cols leaves.
+func (cfg Config) Cost() int {
+	perOp := cfg.MaxCols*(2+cfg.MaxPredicates) + 1
+
+	slots := cfg.MaxHandlers * cfg.MaxOps
+
+	trees := cfg.MaxCols * cfg.MaxMerkleFilters
+
+	return cfg.MaxRows*(cfg.MaxCols+slots*perOp+trees) + slots*cfg.MaxGroups
+}
+
+// Name returns a file-system friendly identifier of the shape
+// e.g. r256_c16_g32_o4_h4_p4_m1, suffixed with the selector unless SELECTOR_MUX
+func (cfg Config) Name() string {
+	name := fmt.Sprintf("r%d_c%d_g%d_o%d_h%d_p%d_m%d",
+		cfg.MaxRows, cfg.MaxCols, cfg.MaxGroups, cfg.MaxOps, cfg.MaxHandlers, cfg.MaxPredicates, cfg.MaxMerkleFilters)
+
+	switch cfg.Selector {
+	case lib.SELECTOR_ONE_HOT:
+		name += "_onehot"
+	case lib.SELECTOR_LOOKUP:
+		name += "_lookup"
+	}
+
+	return name
+}
+
+// Name returns the cache name of a circuit: the shape name, plus the plan
+// hash for specialized circuits (e.g. r64_c8_g8_o4_h4_p4_m1_plan followed by 32 hex digits)
+func (c *SimpleVerifierCircuit) Name() string {
+	if c.Plan == nil {
+		return c.Config.Name()
+	}
+
+	return c.Config.Name() + "_plan" + c.Plan.Hash()
+}
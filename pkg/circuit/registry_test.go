@@ -0,0 +1,156 @@
+package circuit
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"simple-verifier-gnark/pkg/lib"
+)
+
+func TestSelectShape(t *testing.T) {
+	base := Requirements{Rows: 10, Cols: 2, Groups: 2, Ops: 1, Handlers: 1, Predicates: 1}
+
+	cases := []struct {
+		name   string
+		modify func(req *Requirements)
+		want   string
+	}{
+		{"smallest shape", func(req *Requirements) {}, "r64_c4_g8_o2_h1_p2_m1"},
+		{"exact fit", func(req *Requirements) { req.Rows, req.Cols, req.Groups, req.Ops = 64, 4, 8, 2 }, "r64_c4_g8_o2_h1_p2_m1"},
+		{"more columns", func(req *Requirements) { req.Cols = 5 }, "r64_c8_g8_o4_h4_p4_m1"},
+		{"more handlers", func(req *Requirements) { req.Handlers = 3 }, "r64_c8_g8_o4_h4_p4_m1"},
+		{"more rows", func(req *Requirements) { req.Rows = 65 }, "r256_c16_g32_o4_h4_p4_m1"},
+		{"many groups", func(req *Requirements) { req.Rows, req.Groups = 1000, 100 }, "r4096_c8_g4096_o2_h2_p2_m1"},
+		{"many columns", func(req *Requirements) { req.Rows, req.Cols = 1000, 20 }, "r1024_c32_g64_o4_h4_p4_m1"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := base
+
+			tc.modify(&req)
+
+			cfg, err := SelectShape(req)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if cfg.Name() != tc.want {
+				t.Errorf("got %s, want %s", cfg.Name(), tc.want)
+			}
+
+			// No fitting shape is cheaper
+			for _, shape := range Shapes() {
+				if shape.Fits(req) && shape.Cost() < cfg.Cost() {
+					t.Errorf("%s fits and costs %d < %d", shape.Name(), shape.Cost(), cfg.Cost())
+				}
+			}
+		})
+	}
+
+	for _, req := range []Requirements{
+		{Rows: 1 << 13, Cols: 1},
+		{Rows: 1, Cols: 33},
+		{Rows: 1, Cols: 1, MerkleFilters: 2},
+	} {
+		if cfg, err := SelectShape(req); err == nil || !strings.Contains(err.Error(), "no shape fits") {
+			t.Errorf("%+v: got %s, %v", req, cfg.Name(), err)
+		}
+	}
+}
+
+func TestRegisterShape(t *testing.T) {
+	saved := Shapes()
+
+	defer func() { shapes = saved }()
+
+	tiny := Config{MaxRows: 8, MaxCols: 2, MaxGroups: 2, MaxOps: 1, MaxHandlers: 1, MaxPredicates: 1}
+
+	for range 2 {
+		if err := RegisterShape(tiny); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if registered := Shapes(); len(registered) != len(saved)+1 || registered[0] != tiny {
+		t.Fatalf("tiny shape not registered once, cheapest first: %v", registered)
+	}
+
+	if cfg, err := SelectShape(Requirements{Rows: 4, Cols: 2, Groups: 2, Ops: 1, Handlers: 1, Predicates: 1}); err != nil || cfg != tiny {
+		t.Errorf("got %s, %v, want %s", cfg.Name(), err, tiny.Name())
+	}
+
+	if err := RegisterShape(Config{MaxRows: 8, MaxCols: 2, MaxGroups: 1, MaxOps: 1, MaxHandlers: 1, MaxPredicates: 1}); err == nil {
+		t.Error("invalid shape registered")
+	}
+}
+
+// Run with -race: registration may happen while jobs select shapes
+func TestRegisterShapeConcurrently(t *testing.T) {
+	saved := Shapes()
+
+	defer func() { shapes = saved }()
+
+	var wg sync.WaitGroup
+
+	for i := range 8 {
+		wg.Add(2)
+
+		go func() {
+			defer wg.Done()
+
+			if err := RegisterShape(Config{MaxRows: 8 << i, MaxCols: 2, MaxGroups: 2, MaxOps: 1, MaxHandlers: 1, MaxPredicates: 1}); err != nil {
+				t.Error(err)
+			}
+		}()
+
+		go func() {
+			defer wg.Done()
+
+			if _, err := SelectShape(Requirements{Rows: 10, Cols: 2}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if n := len(Shapes()); n != len(saved)+8 {
+		t.Errorf("%d shapes registered, want %d", n-len(saved), 8)
+	}
+}
+
+// Names key the on-disk caches (see keystore.Load): changing one orphans every cached key
+func TestShapeName(t *testing.T) {
+	cfg := DefaultConfig()
+
+	if name := cfg.Name(); name != "r256_c16_g32_o4_h4_p4_m1" {
+		t.Errorf("default shape: %s", name)
+	}
+
+	cfg.Selector = lib.SELECTOR_ONE_HOT
+
+	if name := cfg.Name(); name != "r256_c16_g32_o4_h4_p4_m1_onehot" {
+		t.Errorf("one-hot selector: %s", name)
+	}
+
+	cfg.Selector = lib.SELECTOR_LOOKUP
+
+	if name := cfg.Name(); name != "r256_c16_g32_o4_h4_p4_m1_lookup" {
+		t.Errorf("lookup selector: %s", name)
+	}
+
+	small := Shapes()[0]
+
+	if name := New(small).Name(); name != "r64_c4_g8_o2_h1_p2_m1" {
+		t.Errorf("generic circuit: %s", name)
+	}
+
+	plan := Plan{Handlers: [][]PlanOp{{{OpCode: lib.OP_MERKLE16}, {OpCode: lib.OP_SUM_COL, ColX: 1, Filtered: true}}}}
+
+	if name := NewSpecialized(small, plan).Name(); name != "r64_c4_g8_o2_h1_p2_m1_plan87df421118aa739a1af05c6655a010e2" {
+		t.Errorf("specialized circuit: %s", name)
+	}
+}
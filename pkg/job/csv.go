@@ -0,0 +1,180 @@
+package job
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"simple-verifier-gnark/pkg/lib"
+)
+
+// Schema describes the columns of a CSV dataset, read from JSON (see LoadSchema)
+//
+//	{"columns": [{"name": "id", "bits": 32}, {"name": "delta", "bits": 16, "signed": true},
+//	             {"name": "price", "bits": 32, "scale": 100}]}
+//
+// Names select the CSV columns by header, in schema order; the type of a column
+// is its width (bits), signedness and fixed-point scale (see Column).
+type Schema struct {
+	Columns []Column `json:"columns"`
+}
+
+// LoadSchema reads a JSON schema file (unknown fields are rejected)
+func LoadSchema(path string) (Schema, error) {
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		return Schema{}, fmt.Errorf("schema: %w", err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+
+	dec.DisallowUnknownFields()
+
+	var schema Schema
+
+	if err := dec.Decode(&schema); err != nil {
+		return Schema{}, fmt.Errorf("schema: %w", err)
+	}
+
+	return schema, schema.validate()
+}
+
+// validate checks that every column has a unique name and a valid width
+func (s Schema) validate() error {
+	if len(s.Columns) == 0 {
+		return fmt.Errorf("schema: no columns")
+	}
+
+	seen := make(map[string]bool, len(s.Columns))
+
+	for col, column := range s.Columns {
+		if column.Name == "" {
+			return fmt.Errorf("schema: column %d has no name", col)
+		}
+
+		if seen[column.Name] {
+			return fmt.Errorf("schema: duplicate column %q", column.Name)
+		}
+
+		if column.Bits < 0 || column.Bits > lib.VALUE_BITS {
+			return fmt.Errorf("schema: column %q: bits must be in [0, %d] (0 = default), got %d", column.Name, lib.VALUE_BITS, column.Bits)
+		}
+
+		seen[column.Name] = true
+	}
+
+	return nil
+}
+
+// LoadCSV reads a CSV file with schema (see ReadCSV)
+func LoadCSV(path string, schema Schema) (*Job, error) {
+	f, err := os.Open(path)
+
+	if err != nil {
+		return nil, fmt.Errorf("csv: %w", err)
+	}
+
+	defer f.Close()
+
+	return ReadCSV(f, schema)
+}
+
+// ReadCSV reads a CSV dataset into a job holding only its columns and rows
+//
+// The first record is the header. Each schema column is read from the CSV
+// column of the same name (others are ignored) and every cell is range checked
+// against its column; errors report the CSV line. Set Handlers and call Validate
+// to prove queries over it, or call Items for the Items matrix of a shape.
+func ReadCSV(r io.Reader, schema Schema) (*Job, error) {
+	if err := schema.validate(); err != nil {
+		return nil, err
+	}
+
+	reader := csv.NewReader(r)
+
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("csv: empty file")
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("csv: %w", err)
+	}
+
+	index := make(map[string]int, len(header))
+
+	for i, name := range header {
+		name = strings.TrimSpace(name)
+
+		if _, ok := index[name]; ok {
+			return nil, fmt.Errorf("csv: duplicate header %q", name)
+		}
+
+		index[name] = i
+	}
+
+	fields := make([]int, len(schema.Columns))
+
+	for col, column := range schema.Columns {
+		i, ok := index[column.Name]
+
+		if !ok {
+			return nil, fmt.Errorf("csv: column %q not in header", column.Name)
+		}
+
+		fields[col] = i
+	}
+
+	j := &Job{Columns: schema.Columns}
+
+	for {
+		record, err := reader.Read()
+
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("csv: %w", err)
+		}
+
+		line, _ := reader.FieldPos(0)
+
+		row := make([]json.Number, len(fields))
+
+		for col, i := range fields {
+			value := strings.TrimSpace(record[i])
+
+			if value == "" {
+				return nil, fmt.Errorf("csv: line %d: column %q is empty", line, schema.Columns[col].Name)
+			}
+
+			if _, err := j.parseCell(col, json.Number(value)); err != nil {
+				return nil, fmt.Errorf("csv: line %d: %w", line, err)
+			}
+
+			row[col] = json.Number(value)
+		}
+
+		j.Rows = append(j.Rows, row)
+	}
+
+	if len(j.Rows) == 0 {
+		return nil, fmt.Errorf("csv: no rows after the header")
+	}
+
+	if err := j.validateData(); err != nil {
+		return nil, err
+	}
+
+	return j, nil
+}
@@ -0,0 +1,179 @@
+package job_test
+
+import (
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"simple-verifier-gnark/pkg/circuit"
+	"simple-verifier-gnark/pkg/job"
+)
+
+// schema selects id, a signed delta and a fixed-point price
+var schema = job.Schema{Columns: []job.Column{
+	{Name: "id", Bits: 8},
+	{Name: "delta", Bits: 8, Signed: true},
+	{Name: "price", Bits: 16, Scale: 100},
+}}
+
+func TestReadCSV(t *testing.T) {
+	// Columns are matched by header name; unknown columns are ignored
+	data := "price, note, delta, id\n125, x, -3, 1\n250, y, 7, 2\n"
+
+	j, err := job.ReadCSV(strings.NewReader(data), schema)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(j.Rows) != 2 || j.Rows[0][0] != "1" || j.Rows[0][1] != "-3" || j.Rows[1][2] != "250" {
+		t.Fatalf("rows = %v", j.Rows)
+	}
+
+	items, err := j.Items(circuit.Config{MaxRows: 4, MaxCols: 4})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// -3 is stored as its 8-bit two's complement, padding is 0
+	if got := items[1][0].(*big.Int); got.Int64() != 256-3 {
+		t.Errorf("Items[1][0] = %s, want %d", got, 256-3)
+	}
+
+	if items[3][3] != 0 {
+		t.Errorf("Items[3][3] = %v, want 0", items[3][3])
+	}
+}
+
+func TestReadCSVDecimals(t *testing.T) {
+	// Decimals are scaled by their column, integers are the scaled value
+	schema := job.Schema{Columns: []job.Column{
+		{Name: "price", Bits: 16, Scale: 100},
+		{Name: "delta", Bits: 16, Signed: true, Scale: 100},
+	}}
+
+	data := "price,delta\n1.25,-0.5\n125,-50\n2.50,3.1\n"
+
+	j, err := job.ReadCSV(strings.NewReader(data), schema)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := [][]string{{"125", "-50"}, {"125", "-50"}, {"250", "310"}}
+
+	for row, cells := range want {
+		for col, cell := range cells {
+			if string(j.Rows[row][col]) != cell {
+				t.Fatalf("rows = %v, want %v", j.Rows, want)
+			}
+		}
+	}
+}
+
+func TestReadCSVErrors(t *testing.T) {
+	cases := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"empty file", "", "csv: empty file"},
+		{"duplicate header", "id,delta,price,id\n1,2,3,4\n", `csv: duplicate header "id"`},
+		{"missing column", "id,delta\n1,2\n", `csv: column "price" not in header`},
+		{"no rows", "id,delta,price\n", "csv: no rows after the header"},
+		{"empty cell", "id,delta,price\n1,2,3\n2,,3\n", `csv: line 3: column "delta" is empty`},
+		{"not an integer", "id,delta,price\n1,2,3e5\n", `csv: line 2: column 2 (price): invalid integer "3e5"`},
+		{"not a decimal", "id,delta,price\n1,2,.5\n", `csv: line 2: column 2 (price): invalid decimal ".5"`},
+		{"decimal in an integer column", "id,delta,price\n1.5,2,3\n", `csv: line 2: column 0 (id): "1.5" has more fractional digits than scale 1 allows`},
+		{"too many decimals", "id,delta,price\n1,2,1.234\n", `csv: line 2: column 2 (price): "1.234" has more fractional digits than scale 100 allows`},
+		{"unsigned out of range", "id,delta,price\n256,2,3\n", "csv: line 2: column 0 (id): 256 out of range [0, 256)"},
+		{"negative unsigned", "id,delta,price\n-1,2,3\n", "csv: line 2: column 0 (id): -1 out of range"},
+		{"signed out of range", "id,delta,price\n1,128,3\n", "csv: line 2: column 1 (delta): 128 out of range [-128, 128)"},
+		{"short record", "id,delta,price\n1,2\n", "csv: record on line 2: wrong number of fields"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := job.ReadCSV(strings.NewReader(tc.data), schema)
+
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("error %v, want %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestLoadSchemaErrors(t *testing.T) {
+	cases := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"no columns", `{"columns": []}`, "schema: no columns"},
+		{"no name", `{"columns": [{"bits": 8}]}`, "schema: column 0 has no name"},
+		{"duplicate name", `{"columns": [{"name": "a"}, {"name": "a"}]}`, `schema: duplicate column "a"`},
+		{"bits above 64", `{"columns": [{"name": "a", "bits": 65}]}`, `schema: column "a": bits must be in [0, 64] (0 = default), got 65`},
+		{"negative bits", `{"columns": [{"name": "a", "bits": -1}]}`, "got -1"},
+		{"unknown field", `{"columns": [{"name": "a", "width": 8}]}`, `schema: json: unknown field "width"`},
+		{"invalid JSON", `{"columns": [`, "schema: unexpected EOF"},
+	}
+
+	dir := t.TempDir()
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(dir, tc.name+".json")
+
+			if err := os.WriteFile(path, []byte(tc.data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := job.LoadSchema(path)
+
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("error %v, want %q", err, tc.want)
+			}
+		})
+	}
+
+	if _, err := job.LoadSchema(filepath.Join(dir, "missing.json")); err == nil || !strings.HasPrefix(err.Error(), "schema: ") {
+		t.Fatalf("missing file: error %v", err)
+	}
+
+	// ReadCSV checks the schema before reading
+	if _, err := job.ReadCSV(strings.NewReader("a\n1\n"), job.Schema{}); err == nil || err.Error() != "schema: no columns" {
+		t.Fatalf("empty schema: error %v", err)
+	}
+}
+
+func TestItemsExceedShape(t *testing.T) {
+	data := "id,delta,price\n1,2,3\n2,3,4\n3,4,5\n"
+
+	j, err := job.ReadCSV(strings.NewReader(data), schema)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name string
+		cfg  circuit.Config
+		want string
+	}{
+		{"rows", circuit.Config{MaxRows: 2, MaxCols: 4}, "job: 3 rows exceed MAX_ROWS=2"},
+		{"columns", circuit.Config{MaxRows: 4, MaxCols: 2}, "job: 3 columns exceed MAX_COLS=2"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := j.Items(tc.cfg)
+
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("error %v, want %q", err, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,584 @@
+package job
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"simple-verifier-gnark/pkg/circuit"
+	"simple-verifier-gnark/pkg/lib"
+	"simple-verifier-gnark/pkg/native"
+	"simple-verifier-gnark/pkg/witness"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/frontend"
+)
+
+// Job is a dataset and the queries to prove over it, read from JSON (see Parse)
+//
+//	{
+//	  "columns":  [{"name": "id", "bits": 8}, {"name": "delta", "bits": 8, "signed": true}, ...],
+//	  "rows":     [[1, -32, ...], ...],
+//	  "handlers": [{"start": 0, "nc": 4, "ops": [
+//	    {"op": "MERKLE16"},
+//	    {"op": "SUM_COL_BY", "x": 1, "y": 2, "groups": [1, 2, 3]},
+//	    {"op": "COUNT", "where": {"and": [{"col": 2, "pred": "EQ", "a": 2}, {"not": {...}}]}}
+//	  ]}]
+//	}
+//
+// Values are decimal integers (JSON numbers or strings); signed columns take
+// negative values and are stored as two's complement of the column width.
+// In a fixed-point column an integer is the scaled value and a decimal such as
+// 1.25 is scaled by the column (125 at scale 100, see ParseDecimal); Validate
+// rewrites Rows as the scaled integers.
+// Instead of "rows", "csv" names a CSV file read with the columns as schema
+// (see ReadCSV; relative to the job file). Results are derived from the data
+// (see Assignment).
+type Job struct {
+	Columns  []Column        `json:"columns"`
+	Rows     [][]json.Number `json:"rows,omitempty"`
+	CSV      string          `json:"csv,omitempty"`
+	Handlers []Handler       `json:"handlers"`
+
+	// values[row][col]: parsed cells (negative in signed columns)
+	values [][]*big.Int
+}
+
+// Column describes one column of the dataset
+type Column struct {
+	Name string `json:"name"`
+
+	// Bits: declared width in [1, VALUE_BITS] (0 = VALUE_BITS)
+	Bits int `json:"bits"`
+
+	// Signed: two's-complement values of Bits width
+	Signed bool `json:"signed"`
+
+	// Scale: fixed-point scale (0 = 1, integer column)
+	Scale uint64 `json:"scale"`
+}
+
+// Handler is a range of columns and the ops run over the dataset
+// Start / NC only bound the MERKLE16 commitment; ops address absolute columns
+type Handler struct {
+	Start int  `json:"start"`
+	NC    int  `json:"nc"`
+	Ops   []Op `json:"ops"`
+}
+
+// Op is one aggregate of a handler
+type Op struct {
+	// Op: opcode name (lib.OpCodeNames, e.g. SUM_COL_BY)
+	Op string `json:"op"`
+
+	// X / Y: column args (OpArgs)
+	X int `json:"x"`
+	Y int `json:"y"`
+
+	// Groups: GROUP BY keys, strictly increasing (GROUP BY ops only)
+	Groups []json.Number `json:"groups,omitempty"`
+
+	// Where: row filter (nil = every row)
+	Where *Filter `json:"where,omitempty"`
+
+	// Label: display name of the result (e.g. the SQL select item)
+	Label string `json:"label,omitempty"`
+}
+
+// Filter is the JSON form of circuit.Filter
+// A leaf is {"col", "pred", "a", "b"}; nodes are {"and": [...]}, {"or": [...]} or {"not": {...}}
+type Filter struct {
+	And []*Filter `json:"and,omitempty"`
+	Or  []*Filter `json:"or,omitempty"`
+	Not *Filter   `json:"not,omitempty"`
+
+	Col  int         `json:"col"`
+	Pred string      `json:"pred,omitempty"`
+	A    json.Number `json:"a,omitempty"`
+	B    json.Number `json:"b,omitempty"`
+}
+
+// Load reads and validates a JSON job file
+func Load(path string) (*Job, error) {
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		return nil, fmt.Errorf("job: %w", err)
+	}
+
+	return parse(data, filepath.Dir(path))
+}
+
+// Parse decodes and validates a JSON job (unknown fields are rejected)
+// A relative "csv" path is read from the working directory
+func Parse(data []byte) (*Job, error) {
+	return parse(data, "")
+}
+
+// parse decodes a JSON job, reads its CSV rows relative to dir and validates it
+func parse(data []byte, dir string) (*Job, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+
+	dec.DisallowUnknownFields()
+
+	var j Job
+
+	if err := dec.Decode(&j); err != nil {
+		return nil, fmt.Errorf("job: %w", err)
+	}
+
+	if j.CSV != "" {
+		if len(j.Rows) > 0 {
+			return nil, fmt.Errorf("job: both rows and csv given")
+		}
+
+		path := j.CSV
+
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+
+		data, err := LoadCSV(path, Schema{Columns: j.Columns})
+
+		if err != nil {
+			return nil, err
+		}
+
+		j.Rows = data.Rows
+	}
+
+	if err := j.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &j, nil
+}
+
+// Validate checks the job and encodes its cells
+// Parse calls it; jobs built in Go must call it before Requirements / Assignment
+func (j *Job) Validate() error {
+	if err := j.validateData(); err != nil {
+		return err
+	}
+
+	if len(j.Handlers) == 0 {
+		return fmt.Errorf("job: no handlers")
+	}
+
+	nCols := len(j.Columns)
+
+	for h, handler := range j.Handlers {
+		if handler.Start < 0 || handler.NC < 0 || handler.Start+handler.NC > nCols {
+			return fmt.Errorf("job: handler %d: columns [%d, %d) out of range", h, handler.Start, handler.Start+handler.NC)
+		}
+
+		for op, o := range handler.Ops {
+			if err := j.validateOp(o); err != nil {
+				return fmt.Errorf("job: handler %d op %d: %w", h, op, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateData checks the columns and rows and encodes the cells (handlers are not read)
+func (j *Job) validateData() error {
+	if len(j.Rows) == 0 {
+		return fmt.Errorf("job: no rows")
+	}
+
+	nCols := len(j.Rows[0])
+
+	if len(j.Columns) == 0 {
+		j.Columns = make([]Column, nCols)
+	}
+
+	if len(j.Columns) != nCols {
+		return fmt.Errorf("job: %d columns declared, rows have %d values", len(j.Columns), nCols)
+	}
+
+	for col, column := range j.Columns {
+		if column.Bits < 0 || column.Bits > lib.VALUE_BITS {
+			return fmt.Errorf("job: column %d (%s): bits must be in [0, %d] (0 = default), got %d", col, column.Name, lib.VALUE_BITS, column.Bits)
+		}
+	}
+
+	j.values = make([][]*big.Int, len(j.Rows))
+
+	for row, values := range j.Rows {
+		if len(values) != nCols {
+			return fmt.Errorf("job: row %d has %d values, expected %d", row, len(values), nCols)
+		}
+
+		j.values[row] = make([]*big.Int, nCols)
+
+		for col, value := range values {
+			cell, err := j.parseCell(col, value)
+
+			if err != nil {
+				return fmt.Errorf("job: row %d: %w", row, err)
+			}
+
+			j.values[row][col] = cell
+			j.Rows[row][col] = json.Number(cell.String())
+		}
+	}
+
+	return nil
+}
+
+// validateOp checks the opcode, column args, group keys and filter of an op
+func (j *Job) validateOp(o Op) error {
+	opCode, ok := lib.OpCodeNames[o.Op]
+
+	if !ok {
+		return fmt.Errorf("unknown opcode %q", o.Op)
+	}
+
+	nCols := len(j.Columns)
+
+	if o.X < 0 || o.X >= nCols || o.Y < 0 || o.Y >= nCols {
+		return fmt.Errorf("column args (%d, %d) out of range", o.X, o.Y)
+	}
+
+	if len(o.Groups) > 0 && !isGroupBy(opCode) {
+		return fmt.Errorf("groups given for %s", o.Op)
+	}
+
+	if _, err := parseInts(o.Groups); err != nil {
+		return fmt.Errorf("groups: %w", err)
+	}
+
+	if o.Where != nil {
+		if _, err := o.Where.compile(len(j.Columns)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parseCell parses a cell, range checked against the column (see native.Encode)
+func (j *Job) parseCell(col int, value json.Number) (*big.Int, error) {
+	column := j.Columns[col]
+
+	v, err := ParseDecimal(value, column.Scale)
+
+	if err != nil {
+		return nil, fmt.Errorf("column %d (%s): %w", col, column.Name, err)
+	}
+
+	if _, err := native.Encode(v, column.bits(), column.Signed); err != nil {
+		return nil, fmt.Errorf("column %d (%s): %w", col, column.Name, err)
+	}
+
+	return v, nil
+}
+
+// bits returns the declared width of the column
+func (c Column) bits() int {
+	if c.Bits == 0 {
+		return lib.VALUE_BITS
+	}
+
+	return c.Bits
+}
+
+// Requirements returns the smallest shape the job fits in (see circuit.SelectShape)
+func (j *Job) Requirements() circuit.Requirements {
+	req := circuit.Requirements{
+		Rows:       len(j.Rows),
+		Cols:       len(j.Columns),
+		Handlers:   len(j.Handlers),
+		Predicates: 1,
+	}
+
+	for _, handler := range j.Handlers {
+		req.Ops = max(req.Ops, len(handler.Ops))
+
+		for _, o := range handler.Ops {
+			req.Groups = max(req.Groups, len(o.Groups))
+
+			if f, err := o.Where.compile(len(j.Columns)); err == nil && f != nil {
+				req.Predicates = max(req.Predicates, f.Leaves())
+
+				if lib.OpCodeNames[o.Op] == lib.OP_MERKLE16 {
+					req.MerkleFilters++
+				}
+			}
+		}
+	}
+
+	return req
+}
+
+// Plan returns the fixed query plan of the job (see circuit.NewSpecialized)
+func (j *Job) Plan() circuit.Plan {
+	plan := circuit.Plan{Handlers: make([][]circuit.PlanOp, len(j.Handlers))}
+
+	for h, handler := range j.Handlers {
+		plan.Handlers[h] = make([]circuit.PlanOp, len(handler.Ops))
+
+		for op, o := range handler.Ops {
+			plan.Handlers[h][op] = circuit.PlanOp{
+				OpCode:   lib.OpCodeNames[o.Op],
+				ColX:     o.X,
+				ColY:     o.Y,
+				Filtered: o.Where != nil,
+			}
+		}
+	}
+
+	return plan
+}
+
+// Assignment builds the full circuit assignment of the job for a shape and curve
+//
+// Every input is set from the job and unused rows, columns, handlers, ops,
+// groups and predicates are zero padded. DataRoot and the public Results are
+// computed off-chain exactly as circuit.Define does (see native.Evaluate); a row whose
+// GROUP BY key is not listed is rejected here instead of failing the proof.
+func (j *Job) Assignment(cfg circuit.Config, curve ecc.ID) (*circuit.SimpleVerifierCircuit, error) {
+	b, err := j.builder(cfg)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if req := j.Requirements(); !cfg.Fits(req) {
+		return nil, fmt.Errorf("job: needs %dx%d (groups=%d, ops=%d, handlers=%d, predicates=%d, merkle filters=%d), got %s",
+			req.Rows, req.Cols, req.Groups, req.Ops, req.Handlers, req.Predicates, req.MerkleFilters, cfg)
+	}
+
+	for _, handler := range j.Handlers {
+		h := b.AddHandler(handler.Start, handler.NC)
+
+		for _, o := range handler.Ops {
+			op := b.AddOp(h, lib.OpCodeNames[o.Op], o.X, o.Y)
+
+			keys, _ := parseInts(o.Groups)
+
+			b.SetGroups(h, op, keys...)
+
+			f, _ := o.Where.compile(len(j.Columns))
+
+			b.SetFilter(h, op, f)
+		}
+	}
+
+	return b.Assignment(curve)
+}
+
+// Items returns the encoded cells as the Items matrix [col][row] of a shape
+// Unused rows and columns are 0; more rows than MAX_ROWS or columns than
+// MAX_COLS are rejected. Only the columns and rows of j are read.
+func (j *Job) Items(cfg circuit.Config) ([][]frontend.Variable, error) {
+	b, err := j.builder(cfg)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return b.Items()
+}
+
+// builder returns a witness builder holding the columns and rows of j
+func (j *Job) builder(cfg circuit.Config) (*witness.Builder, error) {
+	if j.values == nil {
+		if err := j.validateData(); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(j.Rows) > cfg.MaxRows {
+		return nil, fmt.Errorf("job: %d rows exceed MAX_ROWS=%d of %s", len(j.Rows), cfg.MaxRows, cfg)
+	}
+
+	if len(j.Columns) > cfg.MaxCols {
+		return nil, fmt.Errorf("job: %d columns exceed MAX_COLS=%d of %s", len(j.Columns), cfg.MaxCols, cfg)
+	}
+
+	b := witness.New(cfg)
+
+	for col, column := range j.Columns {
+		b.SetColumn(col, witness.Column{Bits: column.Bits, Signed: column.Signed, Scale: column.Scale})
+	}
+
+	b.SetRows(j.values...)
+
+	return b, nil
+}
+
+// compile converts the JSON filter to a circuit.Filter (nil stays nil)
+// Leaf columns must be among the nCols columns of the job.
+func (f *Filter) compile(nCols int) (*circuit.Filter, error) {
+	if f == nil {
+		return nil, nil
+	}
+
+	set := 0
+
+	for _, isSet := range []bool{f.And != nil, f.Or != nil, f.Not != nil, f.Pred != ""} {
+		if isSet {
+			set++
+		}
+	}
+
+	if set != 1 {
+		return nil, fmt.Errorf("where: a filter has exactly one of and, or, not, pred")
+	}
+
+	out := &circuit.Filter{}
+
+	var err error
+
+	switch {
+	case f.Not != nil:
+		out.Not, err = f.Not.compile(nCols)
+	case f.And != nil:
+		out.And, err = compileAll(f.And, nCols)
+	case f.Or != nil:
+		out.Or, err = compileAll(f.Or, nCols)
+	default:
+		pred, ok := lib.PredicateNames[f.Pred]
+
+		if !ok {
+			return nil, fmt.Errorf("where: unknown predicate %q", f.Pred)
+		}
+
+		if f.Col < 0 || f.Col >= nCols {
+			return nil, fmt.Errorf("where: %s filter on column %d, the job has %d columns", f.Pred, f.Col, nCols)
+		}
+
+		out.Pred, out.Col = pred, f.Col
+
+		out.A, err = parseOptional(f.A)
+
+		if err == nil {
+			out.B, err = parseOptional(f.B)
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if (out.And != nil && len(out.And) == 0) || (out.Or != nil && len(out.Or) == 0) {
+		return nil, fmt.Errorf("where: empty and / or")
+	}
+
+	return out, nil
+}
+
+// compileAll compiles the sub-filters of an and / or node
+func compileAll(filters []*Filter, nCols int) ([]*circuit.Filter, error) {
+	out := make([]*circuit.Filter, len(filters))
+
+	for i, f := range filters {
+		if f == nil {
+			return nil, fmt.Errorf("where: null filter")
+		}
+
+		compiled, err := f.compile(nCols)
+
+		if err != nil {
+			return nil, err
+		}
+
+		out[i] = compiled
+	}
+
+	return out, nil
+}
+
+// parseInts parses decimal integers
+func parseInts(values []json.Number) ([]*big.Int, error) {
+	out := make([]*big.Int, len(values))
+
+	for i, value := range values {
+		v, ok := new(big.Int).SetString(value.String(), 10)
+
+		if !ok {
+			return nil, fmt.Errorf("invalid integer %q", value)
+		}
+
+		out[i] = v
+	}
+
+	return out, nil
+}
+
+// ParseDecimal parses a value of a column with fixed-point scale (0 = 1)
+//
+// An integer is taken as the scaled value itself. A decimal ([-]digits.digits)
+// is multiplied by scale and must stay exact, so at scale 100 it has at most two
+// significant fractional digits: 1.25 is 125, -0.5 is -50 and 1.234 is an error.
+func ParseDecimal(value json.Number, scale uint64) (*big.Int, error) {
+	text := value.String()
+
+	whole, frac, decimal := strings.Cut(text, ".")
+
+	if !decimal {
+		v, ok := new(big.Int).SetString(text, 10)
+
+		if !ok {
+			return nil, fmt.Errorf("invalid integer %q", value)
+		}
+
+		return v, nil
+	}
+
+	digits := strings.TrimPrefix(whole, "-")
+
+	if digits == "" || frac == "" || strings.Trim(digits+frac, "0123456789") != "" {
+		return nil, fmt.Errorf("invalid decimal %q", value)
+	}
+
+	if scale == 0 {
+		scale = 1
+	}
+
+	v, _ := new(big.Int).SetString(whole+frac, 10)
+
+	v.Mul(v, new(big.Int).SetUint64(scale))
+
+	unit := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(len(frac))), nil)
+
+	v, rem := v.QuoRem(v, unit, new(big.Int))
+
+	if rem.Sign() != 0 {
+		return nil, fmt.Errorf("%q has more fractional digits than scale %d allows", value, scale)
+	}
+
+	return v, nil
+}
+
+// parseOptional parses a decimal integer, nil when empty
+func parseOptional(value json.Number) (*big.Int, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	v, ok := new(big.Int).SetString(value.String(), 10)
+
+	if !ok {
+		return nil, fmt.Errorf("where: invalid integer %q", value)
+	}
+
+	return v, nil
+}
+
+// isGroupBy reports whether opCode is a GROUP BY op (reads GroupKeys)
+func isGroupBy(opCode int) bool {
+	switch opCode {
+	case lib.OP_SUM_COL_BY, lib.OP_MIN_COL_BY, lib.OP_MAX_COL_BY, lib.OP_COUNT_BY:
+		return true
+	}
+
+	return false
+}
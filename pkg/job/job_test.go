@@ -0,0 +1,195 @@
+package job_test
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+
+	"simple-verifier-gnark/pkg/circuit"
+	"simple-verifier-gnark/pkg/job"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/test"
+)
+
+// example loads the job run by main.go when -input is not given
+func example(t *testing.T) *job.Job {
+	t.Helper()
+
+	j, err := job.Load("../../examples/job.json")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return j
+}
+
+// column sums column col of the rows of j selected by keep
+func column(t *testing.T, j *job.Job, col int, keep func(row []int64) bool) (sum, count int64) {
+	t.Helper()
+
+	for _, values := range j.Rows {
+		row := make([]int64, len(values))
+
+		for i, v := range values {
+			n, err := v.Int64()
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			row[i] = n
+		}
+
+		if keep(row) {
+			sum += row[col]
+
+			count++
+		}
+	}
+
+	return sum, count
+}
+
+func TestAssignmentMatchesCircuit(t *testing.T) {
+	curve := ecc.BN254
+
+	field := curve.ScalarField()
+
+	j := example(t)
+
+	cfg, err := circuit.SelectShape(j.Requirements())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assignment, err := j.Assignment(cfg, curve)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	all := func(row []int64) bool { return true }
+
+	sumA, count := column(t, j, 1, all)
+
+	sumKey2, _ := column(t, j, 1, func(row []int64) bool { return row[2] == 2 })
+
+	_, inRange := column(t, j, 0, func(row []int64) bool { return row[0] >= 10 && row[0] < 20 })
+
+	sumDelta, _ := column(t, j, 4, all)
+
+	fe := func(v int64) *big.Int {
+		return new(big.Int).Mod(big.NewInt(v), field)
+	}
+
+	want := []struct {
+		name       string
+		h, op, pos int
+		value      *big.Int
+	}{
+		{"COUNT", 0, 1, 0, fe(count)},
+		{"SUM_COL", 1, 0, 0, fe(sumA)},
+		{"SUM_COL WHERE key = 2", 2, 1, 0, fe(sumKey2)},
+		{"COUNT WHERE id in [10, 20)", 2, 2, 0, fe(inRange)},
+		{"SUM_COL of a signed column", 3, 2, 0, fe(sumDelta)},
+		{"MIN_COL of a signed column", 3, 3, 0, fe(-32)},
+	}
+
+	for _, w := range want {
+		if got := assignment.Results[w.h][w.op][w.pos].(*big.Int); got.Cmp(w.value) != 0 {
+			t.Errorf("%s [%d][%d][%d] = %s, want %s", w.name, w.h, w.op, w.pos, got, w.value)
+		}
+	}
+
+	if err := test.IsSolved(circuit.New(cfg), assignment, field); err != nil {
+		t.Fatalf("generic circuit: %v", err)
+	}
+
+	// The specialized circuit checks the same public inputs
+	plan := j.Plan()
+
+	if err := test.IsSolved(circuit.NewSpecialized(cfg, plan), assignment, field); err != nil {
+		t.Fatalf("specialized circuit: %v", err)
+	}
+
+	assignment.Results[1][0][0] = fe(sumA + 1)
+
+	if err := test.IsSolved(circuit.NewSpecialized(cfg, plan), assignment, field); err == nil {
+		t.Fatal("forged SUM_COL accepted")
+	}
+}
+
+func TestFilteredMerkle(t *testing.T) {
+	curve := ecc.BN254
+
+	j, err := job.Parse([]byte(`{
+		"columns": [{"name": "id"}, {"name": "a"}],
+		"rows": [[1, 10], [2, 20], [3, 30]],
+		"handlers": [{"start": 0, "nc": 2, "ops": [
+			{"op": "MERKLE16"},
+			{"op": "MERKLE16", "where": {"col": 0, "pred": "GE", "a": 2}}
+		]}]
+	}`))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := circuit.SelectShape(j.Requirements())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assignment, err := j.Assignment(cfg, curve)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if assignment.Results[0][0][0].(*big.Int).Cmp(assignment.Results[0][1][0].(*big.Int)) == 0 {
+		t.Fatal("filtered root equals the unfiltered root")
+	}
+
+	if err := test.IsSolved(circuit.New(cfg), assignment, curve.ScalarField()); err != nil {
+		t.Fatalf("generic circuit: %v", err)
+	}
+
+	if err := test.IsSolved(circuit.NewSpecialized(cfg, j.Plan()), assignment, curve.ScalarField()); err != nil {
+		t.Fatalf("specialized circuit: %v", err)
+	}
+}
+
+func TestFilterColumnOutOfRange(t *testing.T) {
+	// Column 7 lies within MaxCols but past the job's columns: it would read as all zeros
+	data := `{
+	  "columns": [{"name": "a", "bits": 8}, {"name": "b", "bits": 8}],
+	  "rows": [[1, 2], [3, 4]],
+	  "handlers": [{"start": 0, "nc": 2, "ops": [{"op": "COUNT", "where": %s}]}]
+	}`
+
+	cases := []struct {
+		where string
+		want  string
+	}{
+		{`{"col": 7, "pred": "EQ", "a": 0}`, "where: EQ filter on column 7, the job has 2 columns"},
+		{`{"col": -1, "pred": "EQ", "a": 0}`, "where: EQ filter on column -1, the job has 2 columns"},
+		{`{"or": [{"col": 0, "pred": "LT", "a": 2}, {"not": {"col": 2, "pred": "GE", "a": 1}}]}`, "where: GE filter on column 2, the job has 2 columns"},
+	}
+
+	for _, tc := range cases {
+		_, err := job.Parse([]byte(fmt.Sprintf(data, tc.where)))
+
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("where %s: error %v, want %q", tc.where, err, tc.want)
+		}
+	}
+
+	if _, err := job.Parse([]byte(fmt.Sprintf(data, `{"col": 1, "pred": "EQ", "a": 0}`))); err != nil {
+		t.Fatal(err)
+	}
+}
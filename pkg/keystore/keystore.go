@@ -0,0 +1,406 @@
+package keystore
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"simple-verifier-gnark/pkg/circuit"
+	"simple-verifier-gnark/pkg/lib"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/backend/plonk"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/constraint"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/consensys/gnark/frontend/cs/scs"
+)
+
+// File names inside a shape directory
+const (
+	CIRCUIT_FILE       = "circuit.r1cs"
+	PLONK_CIRCUIT_FILE = "circuit.scs"
+	PROVING_KEY_FILE   = "proving.key"
+	VERIFYING_KEY_FILE = "verifying.key"
+
+	// SRS_FILE: SHA-256 of the SRS file PLONK keys were derived from (see SRSDigest)
+	SRS_FILE = "srs.sha256"
+)
+
+// Proof system constants (Options.Backend)
+const (
+	BACKEND_GROTH16 = 0 // R1CS, circuit-specific trusted setup
+	BACKEND_PLONK   = 1 // sparse R1CS, universal KZG SRS
+)
+
+// Options selects the curve and proof system of Compile, Setup and Load
+type Options struct {
+	// Curve: one of lib.Curves (ecc.UNKNOWN = ecc.BN254)
+	Curve ecc.ID
+
+	// Backend: BACKEND_GROTH16 (default) or BACKEND_PLONK
+	Backend int
+
+	// SRSFile: canonical KZG SRS for PLONK (see LoadSRS)
+	// Empty = in-process unsafe test SRS (unsafekzg), never for production
+	SRSFile string
+}
+
+// CurveID returns the curve of opts (ecc.BN254 when unset)
+func (opts Options) CurveID() ecc.ID {
+	if opts.Curve == ecc.UNKNOWN {
+		return ecc.BN254
+	}
+
+	return opts.Curve
+}
+
+// BackendName returns the display name of a backend (groth16, plonk)
+func BackendName(backend int) string {
+	if backend == BACKEND_PLONK {
+		return "plonk"
+	}
+
+	return "groth16"
+}
+
+// Proof is a Groth16 or PLONK proof
+type Proof interface {
+	io.WriterTo
+	io.ReaderFrom
+}
+
+// Keys holds the compiled constraint system and keys of one circuit
+// PK / VK are groth16 or plonk keys depending on Backend, over Curve
+type Keys struct {
+	Name    string
+	Curve   ecc.ID
+	Backend int
+	CS      constraint.ConstraintSystem
+	PK      interface{ io.WriterTo }
+	VK      interface{ io.WriterTo }
+
+	// SRS: SHA-256 of the PLONK SRS file ("" for groth16 and the unsafe test SRS)
+	SRS string
+
+	// Cached is true when the keys were read from disk (no compile/setup)
+	Cached bool
+}
+
+// Name returns the cache name of a circuit for a curve and backend
+// BN254 Groth16 keeps c.Name(); other curves append the curve (_bls12_381),
+// PLONK appends _plonk, and _unsafe with the test SRS. Keys of an SRS file
+// record its digest (SRS_FILE), checked by Read against opts.SRSFile.
+func Name(c *circuit.SimpleVerifierCircuit, opts Options) string {
+	name := c.Name()
+
+	if curve := opts.CurveID(); curve != ecc.BN254 {
+		name += "_" + curve.String()
+	}
+
+	if opts.Backend == BACKEND_PLONK {
+		name += "_plonk"
+
+		if opts.SRSFile == "" {
+			name += "_unsafe"
+		}
+	}
+
+	return name
+}
+
+// Dir returns the cache directory of a circuit: root/<Name(c, opts)>
+// Specialized circuits (see circuit.NewSpecialized) get their own directory
+func Dir(root string, c *circuit.SimpleVerifierCircuit, opts Options) string {
+	return filepath.Join(root, Name(c, opts))
+}
+
+// Load returns the keys of a circuit from the cache under root
+// On a cache miss the circuit is compiled, set up and written to disk.
+func Load(root string, c *circuit.SimpleVerifierCircuit, opts Options) (*Keys, error) {
+	dir := Dir(root, c, opts)
+
+	if exists(dir, opts.Backend) {
+		keys, err := Read(dir, Name(c, opts), opts)
+
+		if err != nil {
+			return nil, err
+		}
+
+		keys.Cached = true
+
+		return keys, nil
+	}
+
+	keys, err := Setup(c, opts)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := Write(dir, keys); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// Compile compiles a circuit over the scalar field of opts' curve
+// Groth16 uses the R1CS builder, PLONK the sparse R1CS builder
+func Compile(c *circuit.SimpleVerifierCircuit, opts Options) (constraint.ConstraintSystem, error) {
+	curve := opts.CurveID()
+
+	if _, err := lib.Poseidon2RoundsOf(curve); err != nil {
+		return nil, fmt.Errorf("keystore: %w", err)
+	}
+
+	var builder frontend.NewBuilder = r1cs.NewBuilder
+
+	if opts.Backend == BACKEND_PLONK {
+		builder = scs.NewBuilder
+	}
+
+	cs, err := frontend.Compile(curve.ScalarField(), builder, c)
+
+	if err != nil {
+		return nil, fmt.Errorf("keystore: compile %s: %w", c.Name(), err)
+	}
+
+	return cs, nil
+}
+
+// Setup compiles a circuit and runs the setup of opts.Backend
+func Setup(c *circuit.SimpleVerifierCircuit, opts Options) (*Keys, error) {
+	cs, err := Compile(c, opts)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return SetupCS(Name(c, opts), cs, opts)
+}
+
+// SetupCS runs the setup of opts.Backend on a compiled constraint system
+// Groth16 samples circuit-specific keys; PLONK derives them from a KZG SRS
+func SetupCS(name string, cs constraint.ConstraintSystem, opts Options) (*Keys, error) {
+	keys := &Keys{Name: name, Curve: opts.CurveID(), Backend: opts.Backend, CS: cs}
+
+	if opts.Backend == BACKEND_PLONK {
+		canonical, lagrange, err := LoadSRS(cs, keys.Curve, opts.SRSFile)
+
+		if err != nil {
+			return nil, err
+		}
+
+		pk, vk, err := plonk.Setup(cs, canonical, lagrange)
+
+		if err != nil {
+			return nil, fmt.Errorf("keystore: setup %s: %w", name, err)
+		}
+
+		keys.PK, keys.VK = pk, vk
+
+		if keys.SRS, err = SRSDigest(opts.SRSFile); err != nil {
+			return nil, err
+		}
+
+		return keys, nil
+	}
+
+	pk, vk, err := groth16.Setup(cs)
+
+	if err != nil {
+		return nil, fmt.Errorf("keystore: setup %s: %w", name, err)
+	}
+
+	keys.PK, keys.VK = pk, vk
+
+	return keys, nil
+}
+
+// Prove proves a full witness with the keys' backend
+func (k *Keys) Prove(fullWitness witness.Witness) (Proof, error) {
+	if k.Backend == BACKEND_PLONK {
+		return plonk.Prove(k.CS, k.PK.(plonk.ProvingKey), fullWitness)
+	}
+
+	return groth16.Prove(k.CS, k.PK.(groth16.ProvingKey), fullWitness)
+}
+
+// Verify checks a proof of the keys' backend against a public witness
+func (k *Keys) Verify(proof Proof, publicWitness witness.Witness) error {
+	if k.Backend == BACKEND_PLONK {
+		return plonk.Verify(proof.(plonk.Proof), k.VK.(plonk.VerifyingKey), publicWitness)
+	}
+
+	return groth16.Verify(proof.(groth16.Proof), k.VK.(groth16.VerifyingKey), publicWitness)
+}
+
+// Write stores the constraint system and keys in dir
+func Write(dir string, keys *Keys) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("keystore: %w", err)
+	}
+
+	files := []struct {
+		name string
+		obj  io.WriterTo
+	}{
+		{circuitFile(keys.Backend), keys.CS},
+		{PROVING_KEY_FILE, keys.PK},
+		{VERIFYING_KEY_FILE, keys.VK},
+	}
+
+	for _, file := range files {
+		if err := writeFile(filepath.Join(dir, file.name), file.obj); err != nil {
+			return err
+		}
+	}
+
+	if keys.SRS == "" {
+		return nil
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, SRS_FILE), []byte(keys.SRS+"\n"), 0644); err != nil {
+		return fmt.Errorf("keystore: %w", err)
+	}
+
+	return nil
+}
+
+// Read loads the constraint system and keys named name from dir
+// opts selects the curve and backend the files were written with; PLONK keys
+// must come from opts.SRSFile (see checkSRS)
+func Read(dir string, name string, opts Options) (*Keys, error) {
+	curve, backend := opts.CurveID(), opts.Backend
+
+	srs, err := checkSRS(dir, opts)
+
+	if err != nil {
+		return nil, err
+	}
+
+	keys := &Keys{Name: name, Curve: curve, Backend: backend, SRS: srs}
+
+	var cs, pk, vk io.ReaderFrom
+
+	if backend == BACKEND_PLONK {
+		ccs, plonkPK, plonkVK := plonk.NewCS(curve), plonk.NewProvingKey(curve), plonk.NewVerifyingKey(curve)
+
+		keys.CS, keys.PK, keys.VK = ccs, plonkPK, plonkVK
+
+		cs, pk, vk = ccs, plonkPK, plonkVK
+	} else {
+		ccs, groth16PK, groth16VK := groth16.NewCS(curve), groth16.NewProvingKey(curve), groth16.NewVerifyingKey(curve)
+
+		keys.CS, keys.PK, keys.VK = ccs, groth16PK, groth16VK
+
+		cs, pk, vk = ccs, groth16PK, groth16VK
+	}
+
+	files := []struct {
+		name string
+		obj  io.ReaderFrom
+	}{
+		{circuitFile(backend), cs},
+		{PROVING_KEY_FILE, pk},
+		{VERIFYING_KEY_FILE, vk},
+	}
+
+	for _, file := range files {
+		if err := readFile(filepath.Join(dir, file.name), file.obj); err != nil {
+			return nil, err
+		}
+	}
+
+	return keys, nil
+}
+
+// checkSRS returns the SRS digest of opts and checks it against the one stored in dir
+// Keys set up with another SRS file, or without one, are refused rather than reused.
+func checkSRS(dir string, opts Options) (string, error) {
+	if opts.Backend != BACKEND_PLONK || opts.SRSFile == "" {
+		return "", nil
+	}
+
+	want, err := SRSDigest(opts.SRSFile)
+
+	if err != nil {
+		return "", err
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, SRS_FILE))
+
+	if err != nil {
+		return "", fmt.Errorf("keystore: %s has no SRS digest, set up with another SRS than %s: %w", dir, opts.SRSFile, err)
+	}
+
+	if got := strings.TrimSpace(string(data)); got != want {
+		return "", fmt.Errorf("keystore: %s was set up with SRS %s, not %s (%s)", dir, got, opts.SRSFile, want)
+	}
+
+	return want, nil
+}
+
+// circuitFile returns the constraint system file name of a backend
+func circuitFile(backend int) string {
+	if backend == BACKEND_PLONK {
+		return PLONK_CIRCUIT_FILE
+	}
+
+	return CIRCUIT_FILE
+}
+
+// exists reports whether every cache file of a shape directory is present
+func exists(dir string, backend int) bool {
+	for _, name := range []string{circuitFile(backend), PROVING_KEY_FILE, VERIFYING_KEY_FILE} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+func writeFile(path string, obj io.WriterTo) error {
+	f, err := os.Create(path)
+
+	if err != nil {
+		return fmt.Errorf("keystore: %w", err)
+	}
+
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+
+	if _, err := obj.WriteTo(w); err != nil {
+		return fmt.Errorf("keystore: write %s: %w", path, err)
+	}
+
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("keystore: write %s: %w", path, err)
+	}
+
+	return f.Close()
+}
+
+func readFile(path string, obj io.ReaderFrom) error {
+	f, err := os.Open(path)
+
+	if err != nil {
+		return fmt.Errorf("keystore: %w", err)
+	}
+
+	defer f.Close()
+
+	if _, err := obj.ReadFrom(bufio.NewReader(f)); err != nil {
+		return fmt.Errorf("keystore: read %s: %w", path, err)
+	}
+
+	return nil
+}
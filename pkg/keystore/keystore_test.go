@@ -0,0 +1,282 @@
+package keystore_test
+
+import (
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"simple-verifier-gnark/internal/sample"
+	"simple-verifier-gnark/pkg/circuit"
+	"simple-verifier-gnark/pkg/keystore"
+	"simple-verifier-gnark/pkg/lib"
+	"simple-verifier-gnark/pkg/witness"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	gnarkwitness "github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/test/unsafekzg"
+)
+
+// witnesses returns the full and public witness of a MERKLE16 and SUM_COL job on curve,
+// and a public witness of the same job claiming a wrong sum
+func witnesses(t *testing.T, curve ecc.ID) (full, public, forged gnarkwitness.Witness) {
+	t.Helper()
+
+	b := witness.New(sample.Tiny())
+
+	b.SetRows(witness.Ints(1, 10), witness.Ints(2, 20), witness.Ints(3, 30))
+
+	h := b.AddHandler(0, 2)
+
+	b.AddOp(h, lib.OP_MERKLE16)
+
+	b.AddOp(h, lib.OP_SUM_COL, 1)
+
+	assignment, public, err := b.Build(curve)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	field := curve.ScalarField()
+
+	full, err = frontend.NewWitness(assignment, field)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assignment.Results[h][1][0] = big.NewInt(61)
+
+	forged, err = frontend.NewWitness(assignment, field, frontend.PublicOnly())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return full, public, forged
+}
+
+// roundTrip proves the job with keys and checks the proof against the honest and forged public witnesses
+func roundTrip(t *testing.T, keys *keystore.Keys) {
+	t.Helper()
+
+	full, public, forged := witnesses(t, keys.Curve)
+
+	proof, err := keys.Prove(full)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := keys.Verify(proof, public); err != nil {
+		t.Fatalf("honest proof rejected: %v", err)
+	}
+
+	if err := keys.Verify(proof, forged); err == nil {
+		t.Fatal("proof accepted for a forged result")
+	}
+}
+
+func TestSetupProveVerify(t *testing.T) {
+	cases := []struct {
+		name string
+		opts keystore.Options
+	}{
+		{"groth16", keystore.Options{}},
+		// Empty SRSFile: in-process unsafe KZG SRS
+		{"plonk", keystore.Options{Backend: keystore.BACKEND_PLONK}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			keys, err := keystore.Setup(circuit.New(sample.Tiny()), tc.opts)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if keys.Curve != ecc.BN254 || keys.Backend != tc.opts.Backend {
+				t.Fatalf("keys for %s %s", keys.Curve, keystore.BackendName(keys.Backend))
+			}
+
+			roundTrip(t, keys)
+		})
+	}
+}
+
+func TestWriteRead(t *testing.T) {
+	root := t.TempDir()
+
+	opts := keystore.Options{Curve: ecc.BLS12_377}
+
+	c := circuit.New(sample.Tiny())
+
+	if name := keystore.Name(c, opts); name != sample.Tiny().Name()+"_bls12_377" {
+		t.Fatalf("cache name %s", name)
+	}
+
+	keys, err := keystore.Load(root, c, opts)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if keys.Cached {
+		t.Fatal("empty cache reported as hit")
+	}
+
+	// A second Load reads the keys written by the first (keystore.Read)
+	read, err := keystore.Load(root, c, opts)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !read.Cached || read.Curve != ecc.BLS12_377 {
+		t.Fatalf("cached %v, curve %s", read.Cached, read.Curve)
+	}
+
+	full, public, forged := witnesses(t, opts.Curve)
+
+	proof, err := read.Prove(full)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proofFile, publicFile := filepath.Join(root, keystore.PROOF_FILE), filepath.Join(root, keystore.PUBLIC_WITNESS_FILE)
+
+	if err := keystore.WriteProof(proofFile, publicFile, proof, public); err != nil {
+		t.Fatal(err)
+	}
+
+	// The sidecar names the keys of the proof, for verify to check against its own
+	if name, err := keystore.ReadKeyName(proofFile); err != nil || name != "" {
+		t.Fatalf("key name %q before WriteKeyName: %v", name, err)
+	}
+
+	if err := keystore.WriteKeyName(proofFile, read.Name); err != nil {
+		t.Fatal(err)
+	}
+
+	name, err := keystore.ReadKeyName(proofFile)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := filepath.Join(root, name)
+
+	if dir != keystore.Dir(root, c, opts) {
+		t.Fatalf("key name %s read back as %s", read.Name, name)
+	}
+
+	verifier, err := keystore.ReadVerifyingKey(dir, read.Name, opts)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	readProof, readPublic, err := keystore.ReadProof(proofFile, publicFile, opts)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := verifier.Verify(readProof, readPublic); err != nil {
+		t.Fatalf("proof read back rejected: %v", err)
+	}
+
+	if err := verifier.Verify(readProof, forged); err == nil {
+		t.Fatal("proof read back accepted for a forged result")
+	}
+
+	for _, bad := range []string{"", ".", "..", "../keys", "a/b"} {
+		if err := keystore.CheckKeyName(bad); err == nil {
+			t.Errorf("key name %q accepted", bad)
+		}
+	}
+
+	// The files only decode on the curve they were written with
+	if _, err := keystore.ReadVerifyingKey(dir, read.Name, keystore.Options{}); err == nil {
+		t.Error("BLS12-377 verifying key read as BN254")
+	}
+}
+
+func TestSRSIdentity(t *testing.T) {
+	root := t.TempDir()
+
+	c := circuit.New(sample.Tiny())
+
+	unsafe := keystore.Options{Backend: keystore.BACKEND_PLONK}
+
+	cs, err := keystore.Compile(c, unsafe)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// srs writes the canonical SRS of a toxic value to a file
+	srs := func(name string, toxic int64) string {
+		canonical, _, err := unsafekzg.NewSRS(cs, unsafekzg.WithToxicValue(big.NewInt(toxic)))
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		f, err := os.Create(filepath.Join(root, name))
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		defer f.Close()
+
+		if _, err := canonical.WriteTo(f); err != nil {
+			t.Fatal(err)
+		}
+
+		return f.Name()
+	}
+
+	opts := keystore.Options{Backend: keystore.BACKEND_PLONK, SRSFile: srs("a.srs", 2)}
+
+	// The test SRS gets its own cache: its keys are never reused for an SRS file
+	if name := keystore.Name(c, unsafe); name != sample.Tiny().Name()+"_plonk_unsafe" {
+		t.Fatalf("cache name %s", name)
+	}
+
+	if name := keystore.Name(c, opts); name != sample.Tiny().Name()+"_plonk" {
+		t.Fatalf("cache name %s", name)
+	}
+
+	if _, err := keystore.Load(root, c, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := keystore.Load(root, c, opts)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !keys.Cached || len(keys.SRS) != 64 {
+		t.Fatalf("cached %v, srs %q", keys.Cached, keys.SRS)
+	}
+
+	roundTrip(t, keys)
+
+	// Keys of a.srs are refused for b.srs
+	other := keystore.Options{Backend: keystore.BACKEND_PLONK, SRSFile: srs("b.srs", 3)}
+
+	if _, err := keystore.Load(root, c, other); err == nil || !strings.Contains(err.Error(), "was set up with SRS "+keys.SRS) {
+		t.Fatalf("keys of another SRS: error %v", err)
+	}
+
+	if _, err := keystore.ReadVerifyingKey(keystore.Dir(root, c, other), keys.Name, other); err == nil {
+		t.Fatal("verifying key of another SRS accepted")
+	}
+}
@@ -0,0 +1,137 @@
+package keystore
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/backend/plonk"
+	"github.com/consensys/gnark/backend/witness"
+)
+
+// Default file names of a proof and its public witness (see WriteProof)
+const (
+	PROOF_FILE          = "proof.bin"
+	PUBLIC_WITNESS_FILE = "public.wtns"
+
+	// KEY_NAME_SUFFIX: sidecar of a proof file holding the cache name of its keys (proof.bin.keyname)
+	KEY_NAME_SUFFIX = ".keyname"
+)
+
+// NewProof returns an empty proof of opts' curve and backend, to read into
+func NewProof(opts Options) Proof {
+	if opts.Backend == BACKEND_PLONK {
+		return plonk.NewProof(opts.CurveID())
+	}
+
+	return groth16.NewProof(opts.CurveID())
+}
+
+// ReadVerifyingKey loads only the verifying key of a shape directory
+// Enough for Keys.Verify, without reading the (large) constraint system and proving key
+func ReadVerifyingKey(dir string, name string, opts Options) (*Keys, error) {
+	srs, err := checkSRS(dir, opts)
+
+	if err != nil {
+		return nil, err
+	}
+
+	keys := &Keys{Name: name, Curve: opts.CurveID(), Backend: opts.Backend, SRS: srs}
+
+	if opts.Backend == BACKEND_PLONK {
+		vk := plonk.NewVerifyingKey(keys.Curve)
+
+		if err := readFile(filepath.Join(dir, VERIFYING_KEY_FILE), vk); err != nil {
+			return nil, err
+		}
+
+		keys.VK = vk
+
+		return keys, nil
+	}
+
+	vk := groth16.NewVerifyingKey(keys.Curve)
+
+	if err := readFile(filepath.Join(dir, VERIFYING_KEY_FILE), vk); err != nil {
+		return nil, err
+	}
+
+	keys.VK = vk
+
+	return keys, nil
+}
+
+// WriteProof stores a proof and its public witness (binary WriteTo formats)
+func WriteProof(proofFile, publicFile string, proof Proof, publicWitness witness.Witness) error {
+	if err := writeFile(proofFile, proof); err != nil {
+		return err
+	}
+
+	return writeFile(publicFile, publicWitness)
+}
+
+// ReadProof loads a proof and public witness written by WriteProof
+func ReadProof(proofFile, publicFile string, opts Options) (Proof, witness.Witness, error) {
+	proof := NewProof(opts)
+
+	if err := readFile(proofFile, proof); err != nil {
+		return nil, nil, err
+	}
+
+	publicWitness, err := witness.New(opts.CurveID().ScalarField())
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("keystore: %w", err)
+	}
+
+	if err := readFile(publicFile, publicWitness); err != nil {
+		return nil, nil, err
+	}
+
+	return proof, publicWitness, nil
+}
+
+// WriteKeyName records next to proofFile the cache name of the keys that verify it (see Name)
+// The sidecar travels with the proof, so a verifier only compares it with the key it
+// chose itself (see ReadKeyName) and never reads the key it names.
+func WriteKeyName(proofFile, name string) error {
+	if err := os.WriteFile(proofFile+KEY_NAME_SUFFIX, []byte(name+"\n"), 0644); err != nil {
+		return fmt.Errorf("keystore: %w", err)
+	}
+
+	return nil
+}
+
+// ReadKeyName returns the key name written by WriteKeyName, or "" when proofFile has no sidecar
+func ReadKeyName(proofFile string) (string, error) {
+	data, err := os.ReadFile(proofFile + KEY_NAME_SUFFIX)
+
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("keystore: %w", err)
+	}
+
+	name := strings.TrimSpace(string(data))
+
+	if err := CheckKeyName(name); err != nil {
+		return "", fmt.Errorf("%w in %s", err, proofFile+KEY_NAME_SUFFIX)
+	}
+
+	return name, nil
+}
+
+// CheckKeyName checks that name is a single cache directory name (see Name)
+func CheckKeyName(name string) error {
+	if name == "" || name != filepath.Base(name) || name == "." || name == ".." {
+		return fmt.Errorf("keystore: invalid key name %q", name)
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+package keystore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	kzg_bls12377 "github.com/consensys/gnark-crypto/ecc/bls12-377/kzg"
+	kzg_bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381/kzg"
+	kzg_bn254 "github.com/consensys/gnark-crypto/ecc/bn254/kzg"
+	kzg_bw6761 "github.com/consensys/gnark-crypto/ecc/bw6-761/kzg"
+	"github.com/consensys/gnark-crypto/kzg"
+	"github.com/consensys/gnark/backend/plonk"
+	"github.com/consensys/gnark/constraint"
+	"github.com/consensys/gnark/test/unsafekzg"
+)
+
+// LoadSRS returns the canonical and Lagrange KZG SRS sized for a PLONK constraint system
+//
+// srsFile holds a canonical SRS of curve (kzg.SRS WriteTo format, e.g. converted from a
+// ceremony) with at least plonk.SRSSize points; the Lagrange form is derived from it.
+// An empty srsFile generates an unsafe test SRS in process (unsafekzg, cached in
+// ~/.gnark/kzg): its toxic waste is known, so proofs are only meaningful for tests.
+func LoadSRS(cs constraint.ConstraintSystem, curve ecc.ID, srsFile string) (kzg.SRS, kzg.SRS, error) {
+	if srsFile == "" {
+		canonical, lagrange, err := unsafekzg.NewSRS(cs, unsafekzg.WithFSCache())
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("keystore: test srs: %w", err)
+		}
+
+		return canonical, lagrange, nil
+	}
+
+	canonical := kzg.NewSRS(curve)
+
+	if err := readFile(srsFile, canonical); err != nil {
+		return nil, nil, err
+	}
+
+	sizeCanonical, sizeLagrange := plonk.SRSSize(cs)
+
+	lagrange, err := toLagrange(canonical, sizeCanonical, sizeLagrange)
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("keystore: srs %s: %w", srsFile, err)
+	}
+
+	return canonical, lagrange, nil
+}
+
+// SRSDigest returns the hex SHA-256 of an SRS file, "" for the unsafe test SRS
+func SRSDigest(srsFile string) (string, error) {
+	if srsFile == "" {
+		return "", nil
+	}
+
+	f, err := os.Open(srsFile)
+
+	if err != nil {
+		return "", fmt.Errorf("keystore: %w", err)
+	}
+
+	defer f.Close()
+
+	h := sha256.New()
+
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("keystore: srs %s: %w", srsFile, err)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// toLagrange checks a canonical SRS has sizeCanonical points and derives
+// the Lagrange form of its first sizeLagrange points
+func toLagrange(canonical kzg.SRS, sizeCanonical, sizeLagrange int) (kzg.SRS, error) {
+	switch srs := canonical.(type) {
+	case *kzg_bn254.SRS:
+		if err := checkPoints(len(srs.Pk.G1), sizeCanonical); err != nil {
+			return nil, err
+		}
+
+		lagrangeG1, err := kzg_bn254.ToLagrangeG1(srs.Pk.G1[:sizeLagrange])
+
+		return &kzg_bn254.SRS{Pk: kzg_bn254.ProvingKey{G1: lagrangeG1}, Vk: srs.Vk}, err
+	case *kzg_bls12381.SRS:
+		if err := checkPoints(len(srs.Pk.G1), sizeCanonical); err != nil {
+			return nil, err
+		}
+
+		lagrangeG1, err := kzg_bls12381.ToLagrangeG1(srs.Pk.G1[:sizeLagrange])
+
+		return &kzg_bls12381.SRS{Pk: kzg_bls12381.ProvingKey{G1: lagrangeG1}, Vk: srs.Vk}, err
+	case *kzg_bls12377.SRS:
+		if err := checkPoints(len(srs.Pk.G1), sizeCanonical); err != nil {
+			return nil, err
+		}
+
+		lagrangeG1, err := kzg_bls12377.ToLagrangeG1(srs.Pk.G1[:sizeLagrange])
+
+		return &kzg_bls12377.SRS{Pk: kzg_bls12377.ProvingKey{G1: lagrangeG1}, Vk: srs.Vk}, err
+	case *kzg_bw6761.SRS:
+		if err := checkPoints(len(srs.Pk.G1), sizeCanonical); err != nil {
+			return nil, err
+		}
+
+		lagrangeG1, err := kzg_bw6761.ToLagrangeG1(srs.Pk.G1[:sizeLagrange])
+
+		return &kzg_bw6761.SRS{Pk: kzg_bw6761.ProvingKey{G1: lagrangeG1}, Vk: srs.Vk}, err
+	}
+
+	return nil, fmt.Errorf("unsupported srs type %T", canonical)
+}
+
+func checkPoints(nbPoints, sizeCanonical int) error {
+	if nbPoints < sizeCanonical {
+		return fmt.Errorf("has %d points, circuit needs %d", nbPoints, sizeCanonical)
+	}
+
+	return nil
+}
@@ -1,8 +1,9 @@
 package lib
 
-// Circuit configuration constants
-// Configuration: MAX_ROWS=256, MAX_COLS=16, MAX_GROUPS=32, MAX_OPS=4, MAX_HANDLERS=4
+// Default circuit configuration constants (see circuit.DefaultConfig)
+// Configuration: MAX_ROWS=256, MAX_COLS=16, MAX_GROUPS=32, MAX_OPS=4, MAX_HANDLERS=4, MAX_PREDICATES=4
 // This matches the circom circuit: SimpleVerifier(256, 16, 32, 4, 4)
+// Other shapes are built with circuit.New(circuit.Config{...})
 const (
 	MAX_ROWS     = 256
 	MAX_COLS     = 16
@@ -11,17 +12,90 @@ const (
 	MAX_HANDLERS = 4
 
 	// For 16-ary Merkle tree: 16^3 = 4096 leaves
-	// TOTAL_ITEMS = 256 * 16 = 4096, so N_LEVELS = 3
+	// TOTAL_ITEMS = 256 * 16 = 4096, so N_LEVELS = 3 (see MerkleLevels for other shapes)
 	N_LEVELS = 3
 
 	TOTAL_ITEMS = MAX_COLS * MAX_ROWS // 4096
+
+	// Row filter predicates per op (leaves of a complete binary tree, power of 2)
+	MAX_PREDICATES = 4
+
+	// Filtered MERKLE16 ops per proof (each hashes a tree of its own rows)
+	MAX_MERKLE_FILTERS = 1
+
+	// Maximum declared column bit width (valid cells are < 2^VALUE_BITS)
+	VALUE_BITS = 64
+
+	// Bit width of row counts (COUNT <= rows < 2^COUNT_BITS for every shape)
+	COUNT_BITS = 16
 )
 
 // OpCode constants (fixed - matching circom)
 const (
-	OP_NOOP       = 0
-	OP_MERKLE16   = 1000
-	OP_COUNT      = 2000
-	OP_SUM_COL    = 2001
-	OP_SUM_COL_BY = 3000
+	OP_NOOP        = 0
+	OP_MERKLE16    = 1000
+	OP_COUNT       = 2000
+	OP_SUM_COL     = 2001
+	OP_MIN_COL     = 2002
+	OP_MAX_COL     = 2003
+	OP_AVG_COL     = 2004
+	OP_SUM_PRODUCT = 2005
+	OP_SUM_COL_BY  = 3000
+	OP_MIN_COL_BY  = 3001
+	OP_MAX_COL_BY  = 3002
+	OP_COUNT_BY    = 3003
 )
+
+// Row filter predicate constants (FilterOps)
+// Predicates compare col[k] against public operands [a, b]
+const (
+	PRED_NONE  = 0 // always true
+	PRED_EQ    = 1 // col[k] == a
+	PRED_NE    = 2 // col[k] != a
+	PRED_LT    = 3 // col[k] < a
+	PRED_LE    = 4 // col[k] <= a
+	PRED_GT    = 5 // col[k] > a
+	PRED_GE    = 6 // col[k] >= a
+	PRED_RANGE = 7 // a <= col[k] < b
+)
+
+// Filter tree join constants (FilterJoins)
+const (
+	JOIN_AND = 0
+	JOIN_OR  = 1
+)
+
+// Column selector constants (see ColumnSelector)
+const (
+	SELECTOR_MUX     = 0 // lib.Selector per row
+	SELECTOR_ONE_HOT = 1 // one-hot column vector shared across rows
+	SELECTOR_LOOKUP  = 2 // log-derivative lookup table over all cells
+)
+
+// OpCodeNames maps opcode names (OP_* without the prefix) to opcodes
+var OpCodeNames = map[string]int{
+	"NOOP":        OP_NOOP,
+	"MERKLE16":    OP_MERKLE16,
+	"COUNT":       OP_COUNT,
+	"SUM_COL":     OP_SUM_COL,
+	"MIN_COL":     OP_MIN_COL,
+	"MAX_COL":     OP_MAX_COL,
+	"AVG_COL":     OP_AVG_COL,
+	"SUM_PRODUCT": OP_SUM_PRODUCT,
+	"SUM_COL_BY":  OP_SUM_COL_BY,
+	"MIN_COL_BY":  OP_MIN_COL_BY,
+	"MAX_COL_BY":  OP_MAX_COL_BY,
+	"COUNT_BY":    OP_COUNT_BY,
+}
+
+// PredicateNames maps predicate names (PRED_* without the prefix) to predicates
+var PredicateNames = map[string]int{
+	"NONE":  PRED_NONE,
+	"EQ":    PRED_EQ,
+	"NE":    PRED_NE,
+	"LT":    PRED_LT,
+	"LE":    PRED_LE,
+	"GT":    PRED_GT,
+	"GE":    PRED_GE,
+	"RANGE": PRED_RANGE,
+}
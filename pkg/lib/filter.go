@@ -0,0 +1,179 @@
+package lib
+
+import (
+	"github.com/consensys/gnark/frontend"
+)
+
+// FilterTree is a fixed-shape predicate tree over MAX_PREDICATES leaves
+//
+// Nodes are stored in heap order: node 0 is the root, node i has children
+// 2i+1 and 2i+2, and leaf j is node (nLeaves-1)+j.
+//   - Ops/Columns/Args describe the leaf predicates (one per leaf)
+//   - Joins holds JOIN_AND / JOIN_OR per internal node (nLeaves-1 entries)
+//   - Nots holds 1 to negate a node's output, else 0 (2*nLeaves-1 entries)
+//
+// All-zero inputs (PRED_NONE leaves, AND joins, no NOT) select every row.
+type FilterTree struct {
+	Ops     []frontend.Variable
+	Columns [][]frontend.Variable
+	Args    [][2]frontend.Variable
+	Joins   []frontend.Variable
+	Nots    []frontend.Variable
+}
+
+// FilterTreeMask evaluates a predicate tree per row and ANDs it with rowMask
+// mask[i] = rowMask[i] * tree(row i)
+//
+// Returns the filtered mask and 1 if every predicate, join and not flag is valid (else 0)
+func FilterTreeMask(api frontend.API, tree FilterTree, rowMask []frontend.Variable) ([]frontend.Variable, frontend.Variable) {
+	nLeaves := len(tree.Ops)
+
+	nNodes := 2*nLeaves - 1
+
+	n := len(rowMask)
+
+	// nodeValues[node][row], leaves first
+	nodeValues := make([][]frontend.Variable, nNodes)
+
+	validCount := frontend.Variable(0)
+
+	for j := 0; j < nLeaves; j++ {
+		var validPred frontend.Variable
+
+		nodeValues[nLeaves-1+j], validPred = PredicateValues(api, tree.Columns[j], tree.Ops[j], tree.Args[j][0], tree.Args[j][1], rowMask)
+
+		validCount = api.Add(validCount, validPred)
+	}
+
+	isOr := make([]frontend.Variable, nLeaves-1)
+
+	for node := 0; node < nLeaves-1; node++ {
+		isAnd := IsEqual(api, tree.Joins[node], JOIN_AND)
+
+		isOr[node] = IsEqual(api, tree.Joins[node], JOIN_OR)
+
+		validCount = api.Add(validCount, isAnd, isOr[node])
+	}
+
+	for node := 0; node < nNodes; node++ {
+		validCount = api.Add(validCount, IsEqual(api, tree.Nots[node], 0), IsEqual(api, tree.Nots[node], 1))
+	}
+
+	// Children come later in heap order, so evaluate bottom-up
+	for node := nNodes - 1; node >= 0; node-- {
+		// Internal nodes: AND = l*r, OR = l*r + (l + r - 2*l*r)
+		if node < nLeaves-1 {
+			left := nodeValues[2*node+1]
+
+			right := nodeValues[2*node+2]
+
+			nodeValues[node] = make([]frontend.Variable, n)
+
+			for i := 0; i < n; i++ {
+				both := api.Mul(left[i], right[i])
+
+				either := api.Sub(api.Add(left[i], right[i]), api.Mul(2, both))
+
+				nodeValues[node][i] = api.Add(both, api.Mul(isOr[node], either))
+			}
+		}
+
+		// NOT: v + not*(1 - 2v)
+		for i := 0; i < n; i++ {
+			flip := api.Sub(1, api.Mul(2, nodeValues[node][i]))
+
+			nodeValues[node][i] = api.Add(nodeValues[node][i], api.Mul(tree.Nots[node], flip))
+		}
+	}
+
+	mask := make([]frontend.Variable, n)
+
+	for i := 0; i < n; i++ {
+		mask[i] = api.Mul(rowMask[i], nodeValues[0][i])
+	}
+
+	// Every leaf, join and not flag contributes exactly 1 when valid
+	valid := IsEqual(api, validCount, nLeaves+(nLeaves-1)+nNodes)
+
+	return mask, valid
+}
+
+// PredicateMask evaluates a row predicate and ANDs it with rowMask
+// mask[i] = rowMask[i] * pred(column[i], a, b)
+//
+// Returns the filtered mask and 1 if predOp is a known predicate (else 0)
+func PredicateMask(api frontend.API, column []frontend.Variable, predOp, a, b frontend.Variable, rowMask []frontend.Variable) ([]frontend.Variable, frontend.Variable) {
+	values, validPred := PredicateValues(api, column, predOp, a, b, rowMask)
+
+	mask := make([]frontend.Variable, len(values))
+
+	for i := 0; i < len(values); i++ {
+		mask[i] = api.Mul(rowMask[i], values[i])
+	}
+
+	return mask, validPred
+}
+
+// PredicateValues evaluates a row predicate: values[i] = pred(column[i], a, b)
+//
+// predOp is one of the PRED_* constants. When predOp == PRED_NONE the
+// operands are ignored and every value is 1. Otherwise column values and
+// operands are compared with ValueLessThan (signed operands as p - |x|).
+// Rows with rowMask[i] == 0 are compared as 0 (their value is meaningless
+// and must be masked by the caller).
+//
+// Returns the values and 1 if predOp is a known predicate (else 0)
+func PredicateValues(api frontend.API, column []frontend.Variable, predOp, a, b frontend.Variable, rowMask []frontend.Variable) ([]frontend.Variable, frontend.Variable) {
+	isNone := IsEqual(api, predOp, PRED_NONE)
+
+	isEq := IsEqual(api, predOp, PRED_EQ)
+
+	isNe := IsEqual(api, predOp, PRED_NE)
+
+	isLt := IsEqual(api, predOp, PRED_LT)
+
+	isLe := IsEqual(api, predOp, PRED_LE)
+
+	isGt := IsEqual(api, predOp, PRED_GT)
+
+	isGe := IsEqual(api, predOp, PRED_GE)
+
+	isRange := IsEqual(api, predOp, PRED_RANGE)
+
+	validPred := api.Add(isNone, isEq, isNe, isLt, isLe, isGt, isGe, isRange)
+
+	// Operands are zeroed for PRED_NONE so comparisons stay in range
+	active := api.Sub(1, isNone)
+
+	lo := api.Mul(a, active)
+
+	hi := api.Mul(b, active)
+
+	// pred = c0 + eq*cEq + lt*cLt + isRange*(ltHi - lt*ltHi)
+	// with eq = (x == a), lt = (x < a), ltHi = (x < b)
+	c0 := api.Add(isNone, isNe, isGt, isGe)
+
+	cEq := api.Sub(api.Add(isEq, isLe), api.Add(isNe, isGt))
+
+	cLt := api.Sub(api.Add(isLt, isLe), api.Add(isGt, isGe))
+
+	n := len(column)
+
+	values := make([]frontend.Variable, n)
+
+	for i := 0; i < n; i++ {
+		x := api.Mul(column[i], api.Mul(rowMask[i], active))
+
+		eq := IsEqual(api, x, lo)
+
+		lt := ValueLessThan(api, x, lo)
+
+		ltHi := ValueLessThan(api, x, hi)
+
+		rangeTerm := api.Mul(isRange, api.Sub(ltHi, api.Mul(lt, ltHi)))
+
+		values[i] = api.Add(c0, api.Mul(eq, cEq), api.Mul(lt, cLt), rangeTerm)
+	}
+
+	return values, validPred
+}
@@ -0,0 +1,182 @@
+package lib
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/consensys/gnark/constraint/solver"
+)
+
+func init() {
+	solver.RegisterHint(DivModHint, MSBHint, GroupByHint)
+}
+
+// DivModHint computes floor division q = a / b and r = a mod b (out-of-circuit)
+// inputs: [a, b], outputs: [q, r]. a > p/2 is read as a - p; b == 0 is rejected.
+func DivModHint(field *big.Int, inputs []*big.Int, outputs []*big.Int) error {
+	if len(inputs) != 2 || len(outputs) != 2 {
+		return errors.New("DivModHint: expected 2 inputs and 2 outputs")
+	}
+
+	if inputs[1].Sign() == 0 {
+		return errors.New("DivModHint: division by zero")
+	}
+
+	a := new(big.Int).Set(inputs[0])
+
+	if a.Cmp(new(big.Int).Rsh(field, 1)) > 0 {
+		a.Sub(a, field)
+	}
+
+	// Euclidean division: r >= 0 since b > 0
+	outputs[0].DivMod(a, inputs[1], outputs[1])
+
+	outputs[0].Mod(outputs[0], field)
+
+	return nil
+}
+
+// MSBHint returns bit (VALUE_BITS-1) of a VALUE_BITS-bit value (out-of-circuit)
+// inputs: [v], outputs: [msb]
+func MSBHint(field *big.Int, inputs []*big.Int, outputs []*big.Int) error {
+	if len(inputs) != 1 || len(outputs) != 1 {
+		return errors.New("MSBHint: expected 1 input and 1 output")
+	}
+
+	outputs[0].SetUint64(uint64(inputs[0].Bit(VALUE_BITS - 1)))
+
+	return nil
+}
+
+// GroupByHint assigns rows to groups and aggregates them (out-of-circuit)
+// inputs: [extrema, nGroups, x[nRows], y[nRows], mask[nRows], keys[nGroups], groupMask[nGroups]]
+// outputs: counts, sums, mins, maxs, minHits, maxHits [nGroups each], then idx[nRows]
+//
+// A row belongs to the active group (groupMask[g] != 0) whose key equals y; rows
+// without a group get idx 0 and are not aggregated. Extrema (and their hit counts,
+// the number of rows equal to the extremum) are only computed when extrema != 0,
+// comparing values as signed integers (x > p/2 is x - p).
+func GroupByHint(field *big.Int, inputs []*big.Int, outputs []*big.Int) error {
+	if len(inputs) < 2 {
+		return errors.New("GroupByHint: missing inputs")
+	}
+
+	extrema := inputs[0].Sign() != 0
+
+	nGroups := int(inputs[1].Int64())
+
+	nRows := (len(inputs) - 2 - 2*nGroups) / 3
+
+	if nGroups < 1 || nRows < 1 || len(inputs) != 2+3*nRows+2*nGroups || len(outputs) != 6*nGroups+nRows {
+		return errors.New("GroupByHint: inputs and outputs do not match a group by shape")
+	}
+
+	x := inputs[2 : 2+nRows]
+	y := inputs[2+nRows : 2+2*nRows]
+	mask := inputs[2+2*nRows : 2+3*nRows]
+	keys := inputs[2+3*nRows : 2+3*nRows+nGroups]
+	groupMask := inputs[2+3*nRows+nGroups:]
+
+	counts := outputs[0:nGroups]
+	sums := outputs[nGroups : 2*nGroups]
+	mins := outputs[2*nGroups : 3*nGroups]
+	maxs := outputs[3*nGroups : 4*nGroups]
+	minHits := outputs[4*nGroups : 5*nGroups]
+	maxHits := outputs[5*nGroups : 6*nGroups]
+	idx := outputs[6*nGroups:]
+
+	for _, out := range outputs {
+		out.SetInt64(0)
+	}
+
+	// First active group of each key
+	group := make(map[string]int, nGroups)
+
+	for g := nGroups - 1; g >= 0; g-- {
+		if groupMask[g].Sign() != 0 {
+			group[keys[g].String()] = g
+		}
+	}
+
+	half := new(big.Int).Rsh(field, 1)
+
+	signed := func(v *big.Int) *big.Int {
+		s := new(big.Int).Set(v)
+
+		if s.Cmp(half) > 0 {
+			s.Sub(s, field)
+		}
+
+		return s
+	}
+
+	groupMin := make([]*big.Int, nGroups)
+
+	groupMax := make([]*big.Int, nGroups)
+
+	rowGroup := make([]int, nRows)
+
+	for row := 0; row < nRows; row++ {
+		rowGroup[row] = -1
+
+		g, ok := group[y[row].String()]
+
+		if !ok || mask[row].Sign() == 0 {
+			continue
+		}
+
+		rowGroup[row] = g
+
+		idx[row].SetInt64(int64(g))
+
+		counts[g].Add(counts[g], mask[row])
+
+		sums[g].Add(sums[g], new(big.Int).Mul(mask[row], x[row]))
+
+		if !extrema {
+			continue
+		}
+
+		v := signed(x[row])
+
+		if groupMin[g] == nil || v.Cmp(groupMin[g]) < 0 {
+			groupMin[g] = v
+		}
+
+		if groupMax[g] == nil || v.Cmp(groupMax[g]) > 0 {
+			groupMax[g] = v
+		}
+	}
+
+	for row := 0; extrema && row < nRows; row++ {
+		g := rowGroup[row]
+
+		if g < 0 {
+			continue
+		}
+
+		v := signed(x[row])
+
+		if v.Cmp(groupMin[g]) == 0 {
+			minHits[g].Add(minHits[g], big.NewInt(1))
+		}
+
+		if v.Cmp(groupMax[g]) == 0 {
+			maxHits[g].Add(maxHits[g], big.NewInt(1))
+		}
+	}
+
+	for g := 0; g < nGroups; g++ {
+		counts[g].Mod(counts[g], field)
+
+		sums[g].Mod(sums[g], field)
+
+		if groupMin[g] != nil {
+			mins[g].Mod(groupMin[g], field)
+
+			maxs[g].Mod(groupMax[g], field)
+		}
+	}
+
+	return nil
+}
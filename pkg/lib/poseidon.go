@@ -1,23 +1,68 @@
 package lib
 
 import (
+	"fmt"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc"
 	"github.com/consensys/gnark/frontend"
 	"github.com/consensys/gnark/std/hash"
 	"github.com/consensys/gnark/std/permutation/poseidon2"
 )
 
-// BN254 Poseidon2 default parameters (from gnark-crypto bn254/fr/poseidon2)
+// Poseidon2 default parameters (from gnark-crypto <curve>/fr/poseidon2)
 // gnark v0.14.0 bug: NewPoseidon2 only supports BLS12_377
-// Workaround: Call NewPoseidon2FromParameters directly with BN254 params
-const (
-	Poseidon2Width         = 2 // compression mode
-	Poseidon2FullRounds    = 6
-	Poseidon2PartialRounds = 50
-)
+// Workaround: Call NewPoseidon2FromParameters directly with the curve's params
+const Poseidon2Width = 2 // compression mode
+
+// Poseidon2Rounds holds the full and partial rounds of a curve's permutation
+type Poseidon2Rounds struct {
+	Full    int
+	Partial int
+}
+
+// Curves lists the supported curves and their Poseidon2 rounds
+// The off-chain hasher must use the same rounds (see Poseidon2RoundsOf)
+var Curves = map[ecc.ID]Poseidon2Rounds{
+	ecc.BN254:     {Full: 6, Partial: 50},
+	ecc.BLS12_381: {Full: 6, Partial: 50},
+	ecc.BLS12_377: {Full: 6, Partial: 26},
+	ecc.BW6_761:   {Full: 6, Partial: 50},
+}
+
+// Poseidon2RoundsOf returns the Poseidon2 rounds of a supported curve
+func Poseidon2RoundsOf(curve ecc.ID) (Poseidon2Rounds, error) {
+	rounds, ok := Curves[curve]
+
+	if !ok {
+		return Poseidon2Rounds{}, fmt.Errorf("poseidon2: unsupported curve %s", curve)
+	}
+
+	return rounds, nil
+}
 
-// NewPoseidon2Hasher creates Poseidon2 hasher for BN254 using the workaround
+// CurveOf returns the supported curve whose scalar field is field
+func CurveOf(field *big.Int) (ecc.ID, error) {
+	for curve := range Curves {
+		if curve.ScalarField().Cmp(field) == 0 {
+			return curve, nil
+		}
+	}
+
+	return ecc.UNKNOWN, fmt.Errorf("poseidon2: unsupported field %s", field)
+}
+
+// NewPoseidon2Hasher creates Poseidon2 hasher for the compiler's curve using the workaround
 func NewPoseidon2Hasher(api frontend.API) (hash.FieldHasher, error) {
-	perm, err := poseidon2.NewPoseidon2FromParameters(api, Poseidon2Width, Poseidon2FullRounds, Poseidon2PartialRounds)
+	curve, err := CurveOf(api.Compiler().Field())
+
+	if err != nil {
+		return nil, err
+	}
+
+	rounds := Curves[curve]
+
+	perm, err := poseidon2.NewPoseidon2FromParameters(api, Poseidon2Width, rounds.Full, rounds.Partial)
 
 	if err != nil {
 		return nil, err
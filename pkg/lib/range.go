@@ -0,0 +1,122 @@
+package lib
+
+import (
+	"math/big"
+
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/rangecheck"
+)
+
+// ColumnWidth holds the constants derived from a public column bit width
+type ColumnWidth struct {
+	// Shift: 2^(VALUE_BITS - bits), moves the top bit of the column to bit VALUE_BITS-1
+	Shift frontend.Variable
+
+	// Pow: 2^bits, the two's-complement modulus of the column
+	Pow frontend.Variable
+
+	// Valid: 1 if 0 <= bits <= VALUE_BITS, else 0
+	Valid frontend.Variable
+}
+
+// NewColumnWidth derives shift/pow constants for a public bit width
+func NewColumnWidth(api frontend.API, bits frontend.Variable) ColumnWidth {
+	shift := frontend.Variable(0)
+
+	pow := frontend.Variable(0)
+
+	valid := frontend.Variable(0)
+
+	for k := 0; k <= VALUE_BITS; k++ {
+		isK := IsEqual(api, bits, k)
+
+		shift = api.Add(shift, api.Mul(isK, new(big.Int).Lsh(big.NewInt(1), uint(VALUE_BITS-k))))
+
+		pow = api.Add(pow, api.Mul(isK, new(big.Int).Lsh(big.NewInt(1), uint(k))))
+
+		valid = api.Add(valid, isK)
+	}
+
+	return ColumnWidth{
+		Shift: shift,
+		Pow:   pow,
+		Valid: valid,
+	}
+}
+
+// DecodeColumns range-checks every valid cell and decodes signed columns
+//
+// Range: items[col][row] * rowMask[row] < 2^colBits[col], colBits[col] <= VALUE_BITS.
+// Bounded cells make every aggregate an exact integer: with VALUE_BITS = 64 and
+// COUNT_BITS = 16, sums stay below 2^80 in absolute value and never wrap the field.
+//
+// Signed: when colSigned[col] == 1 the cell is a two's-complement pattern of
+// colBits[col] bits and decodes to u - 2^bits if its top bit is set (negative
+// values become field elements p - |x|). Unsigned cells decode to themselves.
+//
+// Returns the decoded matrix (invalid rows are 0). Compare decoded values with
+// ValueLessThan.
+//
+// Per cell: v < 2^VALUE_BITS, then v * 2^(VALUE_BITS - bits) = msb*2^(VALUE_BITS-1) + low
+// with low < 2^(VALUE_BITS-1), which bounds v < 2^bits and exposes the sign bit.
+func DecodeColumns(
+	api frontend.API,
+	items [][]frontend.Variable,
+	colBits []frontend.Variable,
+	colSigned []frontend.Variable,
+	rowMask []frontend.Variable,
+) [][]frontend.Variable {
+	rc := rangecheck.New(api)
+
+	topBit := new(big.Int).Lsh(big.NewInt(1), VALUE_BITS-1)
+
+	nCols := len(items)
+	nRows := len(rowMask)
+
+	values := make([][]frontend.Variable, nCols)
+
+	for col := 0; col < nCols; col++ {
+		values[col] = make([]frontend.Variable, nRows)
+
+		width := NewColumnWidth(api, colBits[col])
+
+		api.AssertIsEqual(width.Valid, 1)
+
+		api.AssertIsBoolean(colSigned[col])
+
+		// Subtracted from negative cells: 2^bits for signed columns, 0 otherwise
+		signedPow := api.Mul(colSigned[col], width.Pow)
+
+		for row := 0; row < nRows; row++ {
+			value := api.Mul(items[col][row], rowMask[row])
+
+			rc.Check(value, VALUE_BITS)
+
+			shifted := api.Mul(value, width.Shift)
+
+			res, err := api.Compiler().NewHint(MSBHint, 1, shifted)
+
+			if err != nil {
+				panic("failed to create msb hint: " + err.Error())
+			}
+
+			msb := res[0]
+
+			api.AssertIsBoolean(msb)
+
+			rc.Check(api.Sub(shifted, api.Mul(msb, topBit)), VALUE_BITS-1)
+
+			values[col][row] = api.Sub(value, api.Mul(msb, signedPow))
+		}
+	}
+
+	return values
+}
+
+// AssertColumnScales checks every fixed-point scale is in [1, 2^VALUE_BITS)
+// A scale of 1 marks a plain integer column
+func AssertColumnScales(api frontend.API, colScales []frontend.Variable) {
+	for col := 0; col < len(colScales); col++ {
+		api.AssertIsEqual(LessThan(api, 0, colScales[col], VALUE_BITS), 1)
+	}
+}
@@ -0,0 +1,133 @@
+package lib
+
+import (
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/lookup/logderivlookup"
+)
+
+// ColumnSelector extracts column colIndex of an items matrix [col][row]
+// column[row] = items[colIndex][row], or 0 for every row if colIndex is out of range.
+// LookupSelector only accepts colIndex below 2^IndexBits(cols), as the circuit bounds
+// its column args (any larger value or p - k is unsatisfiable).
+type ColumnSelector interface {
+	Column(api frontend.API, colIndex frontend.Variable) []frontend.Variable
+}
+
+// NewColumnSelector returns the selector of the given kind (SELECTOR_* constants)
+func NewColumnSelector(api frontend.API, kind int, items [][]frontend.Variable) ColumnSelector {
+	switch kind {
+	case SELECTOR_ONE_HOT:
+		return NewOneHotSelector(items)
+	case SELECTOR_LOOKUP:
+		return NewLookupSelector(api, items)
+	default:
+		return NewMuxSelector(items)
+	}
+}
+
+// MuxSelector runs lib.Selector on every row
+// Cost per column: rows * cols equality checks and products
+type MuxSelector struct {
+	items [][]frontend.Variable
+}
+
+// NewMuxSelector wraps items in a MuxSelector
+func NewMuxSelector(items [][]frontend.Variable) *MuxSelector {
+	return &MuxSelector{items: items}
+}
+
+// Column implements ColumnSelector
+func (s *MuxSelector) Column(api frontend.API, colIndex frontend.Variable) []frontend.Variable {
+	return SelectColumn(api, s.items, colIndex)
+}
+
+// OneHotSelector computes the one-hot vector of colIndex once and shares it across rows
+// Cost per column: cols equality checks + rows * cols products
+type OneHotSelector struct {
+	items [][]frontend.Variable
+}
+
+// NewOneHotSelector wraps items in a OneHotSelector
+func NewOneHotSelector(items [][]frontend.Variable) *OneHotSelector {
+	return &OneHotSelector{items: items}
+}
+
+// Column implements ColumnSelector
+func (s *OneHotSelector) Column(api frontend.API, colIndex frontend.Variable) []frontend.Variable {
+	nCols := len(s.items)
+	nRows := len(s.items[0])
+
+	oneHot := make([]frontend.Variable, nCols)
+
+	for col := 0; col < nCols; col++ {
+		oneHot[col] = IsEqual(api, colIndex, frontend.Variable(col))
+	}
+
+	column := make([]frontend.Variable, nRows)
+
+	for row := 0; row < nRows; row++ {
+		sum := frontend.Variable(0)
+
+		for col := 0; col < nCols; col++ {
+			sum = api.Add(sum, api.Mul(oneHot[col], s.items[col][row]))
+		}
+
+		column[row] = sum
+	}
+
+	return column
+}
+
+// LookupSelector stores the whole matrix once in a log-derivative lookup table
+// (std/lookup/logderivlookup) at index col*rows + row.
+// Cost: rows * cols table entries once, then ~rows lookups per column
+type LookupSelector struct {
+	table logderivlookup.Table
+	nCols int
+	nRows int
+}
+
+// NewLookupSelector inserts every cell of items into a new lookup table
+func NewLookupSelector(api frontend.API, items [][]frontend.Variable) *LookupSelector {
+	nCols := len(items)
+	nRows := len(items[0])
+
+	table := logderivlookup.New(api)
+
+	for col := 0; col < nCols; col++ {
+		for row := 0; row < nRows; row++ {
+			table.Insert(items[col][row])
+		}
+	}
+
+	return &LookupSelector{table: table, nCols: nCols, nRows: nRows}
+}
+
+// Column implements ColumnSelector
+// colIndex is bounded to IndexBits(cols) first: LessThan alone reads p - k as in range.
+// An out-of-range colIndex reads column 0 and is zeroed (the table has no such index)
+func (s *LookupSelector) Column(api frontend.API, colIndex frontend.Variable) []frontend.Variable {
+	bits := IndexBits(s.nCols)
+
+	api.ToBinary(colIndex, bits)
+
+	inRange := LessThan(api, colIndex, frontend.Variable(s.nCols), bits)
+
+	base := api.Mul(api.Mul(colIndex, inRange), s.nRows)
+
+	indices := make([]frontend.Variable, s.nRows)
+
+	for row := 0; row < s.nRows; row++ {
+		indices[row] = api.Add(base, row)
+	}
+
+	values := s.table.Lookup(indices...)
+
+	column := make([]frontend.Variable, s.nRows)
+
+	for row := 0; row < s.nRows; row++ {
+		column[row] = api.Mul(values[row], inRange)
+	}
+
+	return column
+}
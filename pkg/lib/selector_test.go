@@ -0,0 +1,76 @@
+package lib
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/test"
+)
+
+// selectorCircuit checks the column a selector of kind reads at Col
+type selectorCircuit struct {
+	kind int
+
+	Items [2][3]frontend.Variable
+	Col   frontend.Variable
+	Want  [3]frontend.Variable
+}
+
+func (c *selectorCircuit) Define(api frontend.API) error {
+	items := [][]frontend.Variable{c.Items[0][:], c.Items[1][:]}
+
+	column := NewColumnSelector(api, c.kind, items).Column(api, c.Col)
+
+	for row := range column {
+		api.AssertIsEqual(column[row], c.Want[row])
+	}
+
+	return nil
+}
+
+func TestColumnSelectors(t *testing.T) {
+	field := ecc.BN254.ScalarField()
+
+	items := [2][3]frontend.Variable{{1, 2, 3}, {4, 5, 6}}
+
+	zero := [3]frontend.Variable{0, 0, 0}
+
+	// IndexBits(2) = 8: LOOKUP rejects indices from 2^8 up, including p - k
+	cases := []struct {
+		name    string
+		col     any
+		want    [3]frontend.Variable
+		bounded bool
+	}{
+		{"column 1", 1, items[1], false},
+		{"column out of range", 2, zero, false},
+		{"last index of the bound", 255, zero, false},
+		{"first index past the bound", 256, zero, true},
+		{"negative", fe(-1), zero, true},
+		{"p - cols", fe(-2), zero, true},
+		{"very large", new(big.Int).Lsh(big.NewInt(1), 200), zero, true},
+	}
+
+	kinds := []struct {
+		name string
+		kind int
+	}{{"mux", SELECTOR_MUX}, {"onehot", SELECTOR_ONE_HOT}, {"lookup", SELECTOR_LOOKUP}}
+
+	for _, k := range kinds {
+		kind := k.kind
+
+		for _, tc := range cases {
+			t.Run(k.name+"/"+tc.name, func(t *testing.T) {
+				assignment := &selectorCircuit{Items: items, Col: tc.col, Want: tc.want}
+
+				err := test.IsSolved(&selectorCircuit{kind: kind}, assignment, field)
+
+				if rejected := kind == SELECTOR_LOOKUP && tc.bounded; rejected != (err != nil) {
+					t.Fatalf("rejected %v, error %v", rejected, err)
+				}
+			})
+		}
+	}
+}
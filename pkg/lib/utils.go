@@ -1,6 +1,8 @@
 package lib
 
 import (
+	"math/big"
+
 	"github.com/consensys/gnark/frontend"
 )
 
@@ -23,6 +25,27 @@ func Selector(api frontend.API, arr []frontend.Variable, index frontend.Variable
 	return sum
 }
 
+// SelectColumn selects column colIndex of the items matrix [col][row]
+// column[row] = items[colIndex][row] where colIndex is a signal
+func SelectColumn(api frontend.API, items [][]frontend.Variable, colIndex frontend.Variable) []frontend.Variable {
+	nCols := len(items)
+	nRows := len(items[0])
+
+	column := make([]frontend.Variable, nRows)
+
+	for row := 0; row < nRows; row++ {
+		rowValues := make([]frontend.Variable, nCols)
+
+		for col := 0; col < nCols; col++ {
+			rowValues[col] = items[col][row]
+		}
+
+		column[row] = Selector(api, rowValues, colIndex)
+	}
+
+	return column
+}
+
 // RowMask creates mask for valid rows (i < NR)
 // mask[i] = 1 if i < NR, else 0
 // Port of circom RowMask template
@@ -42,7 +65,7 @@ func ColumnMask(api frontend.API, NC frontend.Variable, maxCols int) []frontend.
 	mask := make([]frontend.Variable, maxCols)
 
 	for i := 0; i < maxCols; i++ {
-		mask[i] = LessThan(api, frontend.Variable(i), NC, 8)
+		mask[i] = LessThan(api, frontend.Variable(i), NC, IndexBits(maxCols))
 	}
 
 	return mask
@@ -54,14 +77,17 @@ func ColumnMask(api frontend.API, NC frontend.Variable, maxCols int) []frontend.
 func ColumnMaskWithStart(api frontend.API, startIndex, NC frontend.Variable, maxCols int) []frontend.Variable {
 	mask := make([]frontend.Variable, maxCols)
 
+	// startIndex + NC may reach 2*maxCols
+	bits := IndexBits(2 * maxCols)
+
 	for i := 0; i < maxCols; i++ {
 		// Check: col >= startIndex (startIndex < col + 1)
-		geStart := LessThan(api, startIndex, frontend.Variable(i+1), 8)
+		geStart := LessThan(api, startIndex, frontend.Variable(i+1), bits)
 
 		// Check: col < startIndex + NC
 		endIndex := api.Add(startIndex, NC)
 
-		ltEnd := LessThan(api, frontend.Variable(i), endIndex, 8)
+		ltEnd := LessThan(api, frontend.Variable(i), endIndex, bits)
 
 		// Both conditions must be true
 		mask[i] = api.Mul(geStart, ltEnd)
@@ -91,7 +117,7 @@ func MaskedSum(api frontend.API, values []frontend.Variable, mask []frontend.Var
 // Uses bit decomposition for comparison
 // bits is the bit width for the comparison (must cover max(a, b))
 func LessThan(api frontend.API, a, b frontend.Variable, bits int) frontend.Variable {
-	powerOfTwo := frontend.Variable(1 << bits)
+	powerOfTwo := new(big.Int).Lsh(big.NewInt(1), uint(bits))
 
 	diff := api.Add(api.Sub(b, a), api.Sub(powerOfTwo, 1))
 
@@ -100,19 +126,64 @@ func LessThan(api frontend.API, a, b frontend.Variable, bits int) frontend.Varia
 	return diffBits[bits]
 }
 
+// ValueLessThan returns 1 if a < b for decoded cell values, else 0
+// Values are integers in [-2^(VALUE_BITS-1), 2^VALUE_BITS): unsigned columns
+// and two's-complement signed columns (negatives as field elements p - |x|)
+// Both sides are shifted by 2^(VALUE_BITS-1) and compared on VALUE_BITS+1 bits
+func ValueLessThan(api frontend.API, a, b frontend.Variable) frontend.Variable {
+	offset := new(big.Int).Lsh(big.NewInt(1), VALUE_BITS-1)
+
+	return LessThan(api, api.Add(a, offset), api.Add(b, offset), VALUE_BITS+1)
+}
+
+// DivMod returns q, r with a == q*b + r and 0 <= r < b (floor division)
+// a is read as a signed integer (field elements above p/2 are negative),
+// q must lie in [-2^qBits, 2^qBits) and b fit in rBits bits; b == 0 is unsatisfiable
+func DivMod(api frontend.API, a, b frontend.Variable, qBits, rBits int) (frontend.Variable, frontend.Variable) {
+	res, err := api.Compiler().NewHint(DivModHint, 2, a, b)
+
+	if err != nil {
+		panic("failed to create divmod hint: " + err.Error())
+	}
+
+	q, r := res[0], res[1]
+
+	assertDivMod(api, a, b, q, r, qBits, rBits)
+
+	return q, r
+}
+
+// assertDivMod constrains a == q*b + r with q in [-2^qBits, 2^qBits) and 0 <= r < b
+func assertDivMod(api frontend.API, a, b, q, r frontend.Variable, qBits, rBits int) {
+	qOffset := new(big.Int).Lsh(big.NewInt(1), uint(qBits))
+
+	api.ToBinary(api.Add(q, qOffset), qBits+1)
+
+	// r >= 0: LessThan alone accepts a field element p - k, i.e. a negative r
+	api.ToBinary(r, rBits)
+
+	api.AssertIsEqual(LessThan(api, r, b, rBits), 1)
+
+	// Both ranges keep q*b + r far from the modulus, so the equality holds over the integers
+	api.AssertIsEqual(api.Add(api.Mul(q, b), r), a)
+}
+
 // IsEqual returns 1 if a == b, else 0
 func IsEqual(api frontend.API, a, b frontend.Variable) frontend.Variable {
 	return api.IsZero(api.Sub(a, b))
 }
 
 // CreateFlatMask creates a flat mask array for the items matrix
-// Combines row mask and column mask: flatMask[col*MAX_ROWS + row] = rowMask[row] * colMask[col]
+// Combines row mask and column mask: flatMask[col*nRows + row] = rowMask[row] * colMask[col]
 func CreateFlatMask(api frontend.API, rowMask []frontend.Variable, colMask []frontend.Variable) []frontend.Variable {
-	flatMask := make([]frontend.Variable, TOTAL_ITEMS)
+	nRows := len(rowMask)
+	nCols := len(colMask)
+
+	flatMask := make([]frontend.Variable, nCols*nRows)
 
-	for col := 0; col < MAX_COLS; col++ {
-		for row := 0; row < MAX_ROWS; row++ {
-			idx := col*MAX_ROWS + row
+	for col := 0; col < nCols; col++ {
+		for row := 0; row < nRows; row++ {
+			idx := col*nRows + row
 
 			flatMask[idx] = api.Mul(rowMask[row], colMask[col])
 		}
@@ -122,13 +193,16 @@ func CreateFlatMask(api frontend.API, rowMask []frontend.Variable, colMask []fro
 }
 
 // FlattenItems converts 2D items matrix to 1D array
-// flatItems[col*MAX_ROWS + row] = items[col][row]
-func FlattenItems(items [MAX_COLS][MAX_ROWS]frontend.Variable) []frontend.Variable {
-	flatItems := make([]frontend.Variable, TOTAL_ITEMS)
+// flatItems[col*nRows + row] = items[col][row]
+func FlattenItems(items [][]frontend.Variable) []frontend.Variable {
+	nCols := len(items)
+	nRows := len(items[0])
+
+	flatItems := make([]frontend.Variable, nCols*nRows)
 
-	for col := 0; col < MAX_COLS; col++ {
-		for row := 0; row < MAX_ROWS; row++ {
-			idx := col*MAX_ROWS + row
+	for col := 0; col < nCols; col++ {
+		for row := 0; row < nRows; row++ {
+			idx := col*nRows + row
 
 			flatItems[idx] = items[col][row]
 		}
@@ -149,3 +223,41 @@ func ApplyMask(api frontend.API, items []frontend.Variable, mask []frontend.Vari
 
 	return masked
 }
+
+// ScaleMask multiplies every mask entry by a single flag: scaled[i] = mask[i] * flag
+// Used to switch a whole mask off when the consuming op is not selected
+func ScaleMask(api frontend.API, mask []frontend.Variable, flag frontend.Variable) []frontend.Variable {
+	n := len(mask)
+
+	scaled := make([]frontend.Variable, n)
+
+	for i := 0; i < n; i++ {
+		scaled[i] = api.Mul(mask[i], flag)
+	}
+
+	return scaled
+}
+
+// IndexBits returns the LessThan bit width for indices and counts up to n
+// At least 8 bits (the width used by the default configuration)
+func IndexBits(n int) int {
+	bits := 8
+
+	for (1 << bits) <= n {
+		bits++
+	}
+
+	return bits
+}
+
+// MerkleLevels returns the 16-ary Merkle depth covering totalItems leaves
+// Smallest L with 16^L >= totalItems (missing leaves are padded with 0)
+func MerkleLevels(totalItems int) int {
+	levels := 1
+
+	for leaves := 16; leaves < totalItems; leaves *= 16 {
+		levels++
+	}
+
+	return levels
+}
@@ -0,0 +1,151 @@
+package lib
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/test"
+)
+
+// divModCircuit checks a prover-supplied quotient and remainder
+type divModCircuit struct {
+	A, B, Q, R frontend.Variable
+}
+
+func (c *divModCircuit) Define(api frontend.API) error {
+	assertDivMod(api, c.A, c.B, c.Q, c.R, 8, 8)
+
+	return nil
+}
+
+// hintDivModCircuit checks the quotient and remainder computed by DivMod
+type hintDivModCircuit struct {
+	A, B, Q, R frontend.Variable
+}
+
+func (c *hintDivModCircuit) Define(api frontend.API) error {
+	q, r := DivMod(api, c.A, c.B, 8, 8)
+
+	api.AssertIsEqual(q, c.Q)
+
+	api.AssertIsEqual(r, c.R)
+
+	return nil
+}
+
+// valueLessThanCircuit checks ValueLessThan(A, B) == Less
+type valueLessThanCircuit struct {
+	A, B, Less frontend.Variable
+}
+
+func (c *valueLessThanCircuit) Define(api frontend.API) error {
+	api.AssertIsEqual(ValueLessThan(api, c.A, c.B), c.Less)
+
+	return nil
+}
+
+// fe returns v as a BN254 field element (negatives wrap to p - |v|)
+func fe(v int64) *big.Int {
+	return new(big.Int).Mod(big.NewInt(v), ecc.BN254.ScalarField())
+}
+
+func TestDivMod(t *testing.T) {
+	field := ecc.BN254.ScalarField()
+
+	cases := []struct {
+		name string
+		a, b any
+		q, r any
+	}{
+		{"exact", 12, 3, 4, 0},
+		{"remainder", 10, 3, 3, 1},
+		{"zero", 0, 7, 0, 0},
+		{"negative rounds down", fe(-10), 3, fe(-4), 2},
+		{"divide by one", 200, 1, 200, 0},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assignment := &hintDivModCircuit{A: tc.a, B: tc.b, Q: tc.q, R: tc.r}
+
+			if err := test.IsSolved(&hintDivModCircuit{}, assignment, field); err != nil {
+				t.Fatal(err)
+			}
+
+			direct := &divModCircuit{A: tc.a, B: tc.b, Q: tc.q, R: tc.r}
+
+			if err := test.IsSolved(&divModCircuit{}, direct, field); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestDivModRejectsForgedQuotient(t *testing.T) {
+	field := ecc.BN254.ScalarField()
+
+	cases := []struct {
+		name string
+		a, b any
+		q, r any
+	}{
+		// 10 = 4*3 - 2: only a negative remainder makes the inflated quotient fit
+		{"negative remainder", 10, 3, 4, fe(-2)},
+		{"negative remainder of a negative", fe(-10), 3, fe(-3), fe(-1)},
+		{"remainder equal to divisor", 10, 3, 2, 4},
+		{"remainder above divisor", 10, 3, 1, 7},
+		{"wrong quotient", 10, 3, 2, 1},
+		{"divide by zero", 10, 0, 0, 10},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assignment := &divModCircuit{A: tc.a, B: tc.b, Q: tc.q, R: tc.r}
+
+			if err := test.IsSolved(&divModCircuit{}, assignment, field); err == nil {
+				t.Fatalf("%v = %v*%v + %v accepted", tc.a, tc.q, tc.b, tc.r)
+			}
+		})
+	}
+}
+
+func TestValueLessThan(t *testing.T) {
+	field := ecc.BN254.ScalarField()
+
+	minValue := new(big.Int).Sub(field, new(big.Int).Lsh(big.NewInt(1), VALUE_BITS-1))
+
+	maxValue := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), VALUE_BITS), big.NewInt(1))
+
+	cases := []struct {
+		name string
+		a, b any
+		less int
+	}{
+		{"negative below zero", fe(-1), 0, 1},
+		{"zero above negative", 0, fe(-1), 0},
+		{"negative below positive", fe(-13), 5, 1},
+		{"positive above negative", 5, fe(-13), 0},
+		{"negatives", fe(-13), fe(-5), 1},
+		{"equal negatives", fe(-5), fe(-5), 0},
+		{"domain bounds", minValue, maxValue, 1},
+		{"domain bounds reversed", maxValue, minValue, 0},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assignment := &valueLessThanCircuit{A: tc.a, B: tc.b, Less: tc.less}
+
+			if err := test.IsSolved(&valueLessThanCircuit{}, assignment, field); err != nil {
+				t.Fatal(err)
+			}
+
+			assignment.Less = 1 - tc.less
+
+			if err := test.IsSolved(&valueLessThanCircuit{}, assignment, field); err == nil {
+				t.Fatal("flipped comparison accepted")
+			}
+		})
+	}
+}
@@ -0,0 +1,865 @@
+// Package native evaluates SimpleVerifierCircuit assignments off-chain
+//
+// Every opcode of lib/constants.go, the row filters, column decoding, handler
+// masking (NumHandlers) and column ranges (HandlerStartIndex / HandlerNCs)
+// follow circuit.Define, so Evaluate yields the DataRoot and Results a proof
+// of the assignment must expose. Assignment values may be ints, *big.Int or
+// fr.Element of any supported curve.
+package native
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+
+	"simple-verifier-gnark/pkg/circuit"
+	"simple-verifier-gnark/pkg/lib"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	fr_bls12377 "github.com/consensys/gnark-crypto/ecc/bls12-377/fr"
+	fr_bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
+	fr_bn254 "github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	fr_bw6761 "github.com/consensys/gnark-crypto/ecc/bw6-761/fr"
+	"github.com/consensys/gnark/frontend"
+)
+
+// Evaluate sets the DataRoot and the public Results of an assignment
+// Every other input must be set (see Results)
+func Evaluate(c *circuit.SimpleVerifierCircuit, curve ecc.ID) error {
+	results, err := Results(c, curve)
+
+	if err != nil {
+		return err
+	}
+
+	dataRoot, err := DataRoot(c, curve)
+
+	if err != nil {
+		return err
+	}
+
+	c.DataRoot = dataRoot
+
+	for h := range results {
+		for op := range results[h] {
+			for g, slot := range results[h][op] {
+				c.Results[h][op][g] = slot
+			}
+		}
+	}
+
+	return nil
+}
+
+// Results returns the public Results [handler][op][slot] of an assignment, reduced modulo
+// the scalar field of curve; results of inactive handlers are 0.
+// Inputs the circuit would reject (cells out of range, unknown opcodes or
+// predicates, column args or filter columns outside Items, unlisted or unordered
+// group keys, filter operands or group keys too far apart for lib.ValueLessThan,
+// ops differing from c.Plan) and values of unsupported types are reported as errors.
+//
+// Two differences remain, both on inputs witness.Builder never produces:
+//   - NR, NumHandlers and NumGroups must lie in [0, max]; the circuit's LessThan
+//     masks also accept a few values past either end (read as 0 or max)
+//   - inactive handlers are skipped, while the generic circuit still compares
+//     their filter operands, checks their GROUP BY keys and divides their
+//     SUM_PRODUCT by the Y scale: leave them zeroed
+func Results(c *circuit.SimpleVerifierCircuit, curve ecc.ID) ([][][]*big.Int, error) {
+	cfg := c.Config
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	field := curve.ScalarField()
+
+	nr, err := intValue(c.NR)
+
+	if err != nil {
+		return nil, fmt.Errorf("native: NR: %w", err)
+	}
+
+	if nr < 0 || nr > cfg.MaxRows {
+		return nil, fmt.Errorf("native: NR = %d out of [0, %d]", nr, cfg.MaxRows)
+	}
+
+	values, err := decodeColumns(c, field, nr)
+
+	if err != nil {
+		return nil, err
+	}
+
+	numHandlers, err := intValue(c.NumHandlers)
+
+	if err != nil {
+		return nil, fmt.Errorf("native: NumHandlers: %w", err)
+	}
+
+	if c.Plan != nil && numHandlers != len(c.Plan.Handlers) {
+		return nil, fmt.Errorf("native: %d handlers, query plan has %d", numHandlers, len(c.Plan.Handlers))
+	}
+
+	if err := checkMerkleFilters(c, numHandlers); err != nil {
+		return nil, err
+	}
+
+	results := make([][][]*big.Int, cfg.MaxHandlers)
+
+	for h := 0; h < cfg.MaxHandlers; h++ {
+		results[h] = make([][]*big.Int, cfg.MaxOps)
+
+		for op := 0; op < cfg.MaxOps; op++ {
+			slots := make([]*big.Int, cfg.MaxGroups)
+
+			for g := range slots {
+				slots[g] = big.NewInt(0)
+			}
+
+			if h < numHandlers {
+				if err := evalOp(c, curve, values, nr, h, op, slots); err != nil {
+					return nil, fmt.Errorf("native: handler %d op %d: %w", h, op, err)
+				}
+			}
+
+			// Slots may share the decoded cells: reduce into fresh integers
+			for g, slot := range slots {
+				slots[g] = new(big.Int).Mod(slot, field)
+			}
+
+			results[h][op] = slots
+		}
+	}
+
+	return results, nil
+}
+
+// Encode returns the stored cell of a value in a column of the given width (inverse of lib.DecodeColumns)
+// Unsigned values must be in [0, 2^bits); signed values in [-2^(bits-1), 2^(bits-1))
+// are stored as their two's complement.
+func Encode(value *big.Int, bits int, signed bool) (*big.Int, error) {
+	lo, hi := big.NewInt(0), new(big.Int).Lsh(big.NewInt(1), uint(bits))
+
+	if signed {
+		hi.Rsh(hi, 1)
+
+		lo.Neg(hi)
+	}
+
+	if value.Cmp(lo) < 0 || value.Cmp(hi) >= 0 {
+		return nil, fmt.Errorf("%s out of range [%s, %s)", value, lo, hi)
+	}
+
+	cell := new(big.Int).Set(value)
+
+	if cell.Sign() < 0 {
+		cell.Add(cell, new(big.Int).Lsh(big.NewInt(1), uint(bits)))
+	}
+
+	return cell, nil
+}
+
+// decodeColumns returns the decoded cells [col][row] as signed integers (lib.DecodeColumns)
+// Rows at or beyond nr decode to 0
+func decodeColumns(c *circuit.SimpleVerifierCircuit, field *big.Int, nr int) ([][]*big.Int, error) {
+	cfg := c.Config
+
+	values := make([][]*big.Int, cfg.MaxCols)
+
+	for col := 0; col < cfg.MaxCols; col++ {
+		values[col] = make([]*big.Int, cfg.MaxRows)
+
+		bits, err := intValue(c.ColumnBits[col])
+
+		if err != nil {
+			return nil, fmt.Errorf("native: column %d: bits: %w", col, err)
+		}
+
+		if bits < 0 || bits > lib.VALUE_BITS {
+			return nil, fmt.Errorf("native: column %d: %d bits out of [0, %d]", col, bits, lib.VALUE_BITS)
+		}
+
+		signedFlag, err := bigValue(c.ColumnSigned[col])
+
+		if err != nil {
+			return nil, fmt.Errorf("native: column %d: signed: %w", col, err)
+		}
+
+		signed := signedFlag.Sign() != 0
+
+		pow := new(big.Int).Lsh(big.NewInt(1), uint(bits))
+
+		for row := 0; row < cfg.MaxRows; row++ {
+			values[col][row] = big.NewInt(0)
+
+			if row >= nr {
+				continue
+			}
+
+			raw, err := bigValue(c.Items[col][row])
+
+			if err != nil {
+				return nil, fmt.Errorf("native: row %d column %d: %w", row, col, err)
+			}
+
+			cell := new(big.Int).Mod(raw, field)
+
+			if cell.Cmp(pow) >= 0 {
+				return nil, fmt.Errorf("native: row %d column %d: %s exceeds %d bits", row, col, cell, bits)
+			}
+
+			if signed && bits > 0 && cell.Bit(bits-1) == 1 {
+				cell.Sub(cell, pow)
+			}
+
+			values[col][row] = cell
+		}
+	}
+
+	return values, nil
+}
+
+// evalOp writes the result slots of op slot [h][op]
+func evalOp(c *circuit.SimpleVerifierCircuit, curve ecc.ID, values [][]*big.Int, nr, h, op int, slots []*big.Int) error {
+	field := curve.ScalarField()
+
+	opCode, err := intValue(c.OpCodes[h][op])
+
+	if err != nil {
+		return fmt.Errorf("opcode: %w", err)
+	}
+
+	colX, err := intValue(c.OpArgs[h][op][0])
+
+	if err != nil {
+		return fmt.Errorf("column arg 0: %w", err)
+	}
+
+	colY, err := intValue(c.OpArgs[h][op][1])
+
+	if err != nil {
+		return fmt.Errorf("column arg 1: %w", err)
+	}
+
+	// A plan fixes the opcode and args; unfiltered plan ops run on every valid row
+	// (their filter columns and operands are not read) and only its GROUP BY ops
+	// read the group keys
+	filtered, grouped := true, true
+
+	if c.Plan != nil {
+		planOp := planOp(c.Plan, h, op)
+
+		if opCode != planOp.OpCode || colX != planOp.ColX || colY != planOp.ColY {
+			return fmt.Errorf("op (%d, %d, %d) differs from the query plan (%d, %d, %d)",
+				opCode, colX, colY, planOp.OpCode, planOp.ColX, planOp.ColY)
+		}
+
+		filtered = planOp.OpCode != lib.OP_NOOP && planOp.Filtered
+
+		grouped = isGroupBy(planOp.OpCode)
+
+		empty, err := isEmptyFilter(c, h, op)
+
+		if err != nil {
+			return err
+		}
+
+		if !filtered && !empty {
+			return fmt.Errorf("filter must be empty (unfiltered plan op)")
+		}
+	}
+
+	if colX < 0 || colX >= c.Config.MaxCols || colY < 0 || colY >= c.Config.MaxCols {
+		return fmt.Errorf("column args (%d, %d) out of range", colX, colY)
+	}
+
+	mask := make([]bool, c.Config.MaxRows)
+
+	for row := 0; row < nr; row++ {
+		mask[row] = true
+	}
+
+	if filtered {
+		if mask, err = filterMask(c, field, values, nr, h, op); err != nil {
+			return err
+		}
+	}
+
+	var groups []group
+
+	if grouped {
+		if groups, err = groupBy(c, field, values, mask, h, op, colX, colY); err != nil {
+			return err
+		}
+	}
+
+	switch opCode {
+	case lib.OP_NOOP:
+	case lib.OP_MERKLE16:
+		root, err := merkleRoot(c, curve, mask, h)
+
+		if err != nil {
+			return err
+		}
+
+		slots[0] = root
+	case lib.OP_COUNT:
+		slots[0] = big.NewInt(int64(countRows(mask)))
+	case lib.OP_SUM_COL:
+		slots[0] = sumRows(values[colX], mask)
+	case lib.OP_MIN_COL, lib.OP_MAX_COL:
+		minValue, maxValue := minMaxRows(values[colX], mask)
+
+		slots[0] = minValue
+
+		if opCode == lib.OP_MAX_COL {
+			slots[0] = maxValue
+		}
+	case lib.OP_AVG_COL:
+		// count == 0 divides 0 by 1
+		count := big.NewInt(int64(max(countRows(mask), 1)))
+
+		slots[0].DivMod(sumRows(values[colX], mask), count, slots[1])
+	case lib.OP_SUM_PRODUCT:
+		dot := big.NewInt(0)
+
+		for row, selected := range mask {
+			if selected {
+				dot.Add(dot, new(big.Int).Mul(values[colX][row], values[colY][row]))
+			}
+		}
+
+		scale, err := bigValue(c.ColumnScales[colY])
+
+		if err != nil {
+			return fmt.Errorf("column %d: scale: %w", colY, err)
+		}
+
+		if scale.Sign() <= 0 {
+			return fmt.Errorf("column %d: scale must be >= 1", colY)
+		}
+
+		slots[0].DivMod(dot, scale, slots[1])
+	case lib.OP_SUM_COL_BY, lib.OP_MIN_COL_BY, lib.OP_MAX_COL_BY, lib.OP_COUNT_BY:
+		for g, group := range groups {
+			switch opCode {
+			case lib.OP_SUM_COL_BY:
+				slots[g] = group.sum
+			case lib.OP_MIN_COL_BY:
+				slots[g] = group.min
+			case lib.OP_MAX_COL_BY:
+				slots[g] = group.max
+			case lib.OP_COUNT_BY:
+				slots[g] = big.NewInt(int64(group.count))
+			}
+		}
+	default:
+		return fmt.Errorf("unknown opcode %d", opCode)
+	}
+
+	return nil
+}
+
+// group holds the aggregates of one GROUP BY key
+type group struct {
+	key   *big.Int
+	count int
+	sum   *big.Int
+	min   *big.Int
+	max   *big.Int
+}
+
+// groupBy aggregates column X of the masked rows by the NumGroups keys of column Y
+// Runs whenever NumGroups > 0 (the generic circuit checks row membership for any
+// opcode, a plan only for its GROUP BY ops); keys must be strictly increasing
+// (lib.ValueLessThan) and every masked row must match one.
+// Empty groups report 0 for every aggregate.
+func groupBy(c *circuit.SimpleVerifierCircuit, field *big.Int, values [][]*big.Int, mask []bool, h, op, colX, colY int) ([]group, error) {
+	numGroups, err := intValue(c.NumGroups[h][op])
+
+	if err != nil {
+		return nil, fmt.Errorf("NumGroups: %w", err)
+	}
+
+	if numGroups < 0 || numGroups > c.Config.MaxGroups {
+		return nil, fmt.Errorf("%d groups, shape allows %d", numGroups, c.Config.MaxGroups)
+	}
+
+	groups := make([]group, numGroups)
+
+	index := make(map[string]int, numGroups)
+
+	for g := range groups {
+		raw, err := bigValue(c.GroupKeys[h][op][g])
+
+		if err != nil {
+			return nil, fmt.Errorf("group key %d: %w", g, err)
+		}
+
+		key := signedValue(raw, field)
+
+		if g > 0 {
+			increasing, err := valueLessThan(groups[g-1].key, key, field)
+
+			if err != nil {
+				return nil, fmt.Errorf("group key %d: %w", g, err)
+			}
+
+			if !increasing {
+				return nil, fmt.Errorf("group keys must be strictly increasing (key %d: %s after %s)", g, key, groups[g-1].key)
+			}
+		}
+
+		groups[g] = group{key: key, sum: big.NewInt(0), min: big.NewInt(0), max: big.NewInt(0)}
+
+		index[key.String()] = g
+	}
+
+	if numGroups == 0 {
+		return groups, nil
+	}
+
+	for row, selected := range mask {
+		if !selected {
+			continue
+		}
+
+		g, ok := index[values[colY][row].String()]
+
+		if !ok {
+			return nil, fmt.Errorf("row %d: group key %s not listed", row, values[colY][row])
+		}
+
+		x := values[colX][row]
+
+		if groups[g].count == 0 || x.Cmp(groups[g].min) < 0 {
+			groups[g].min = new(big.Int).Set(x)
+		}
+
+		if groups[g].count == 0 || x.Cmp(groups[g].max) > 0 {
+			groups[g].max = new(big.Int).Set(x)
+		}
+
+		groups[g].count++
+
+		groups[g].sum = new(big.Int).Add(groups[g].sum, x)
+	}
+
+	return groups, nil
+}
+
+// leafPredicate is one checked leaf of a predicate tree
+type leafPredicate struct {
+	col, pred int
+	a, b      *big.Int
+}
+
+// filterMask returns the rows selected by the predicate tree of [h][op] (lib.FilterTreeMask)
+// Leaves, joins and NOT flags are checked up front, as the circuit does even without valid rows.
+func filterMask(c *circuit.SimpleVerifierCircuit, field *big.Int, values [][]*big.Int, nr, h, op int) ([]bool, error) {
+	nLeaves := c.Config.MaxPredicates
+
+	nNodes := 2*nLeaves - 1
+
+	leaves := make([]leafPredicate, nLeaves)
+
+	for j := range leaves {
+		col, err := intValue(c.FilterCols[h][op][j])
+
+		if err != nil {
+			return nil, fmt.Errorf("predicate %d: column: %w", j, err)
+		}
+
+		if col < 0 || col >= c.Config.MaxCols {
+			return nil, fmt.Errorf("predicate %d: column %d out of range", j, col)
+		}
+
+		pred, err := intValue(c.FilterOps[h][op][j])
+
+		if err != nil {
+			return nil, fmt.Errorf("predicate %d: %w", j, err)
+		}
+
+		if pred < lib.PRED_NONE || pred > lib.PRED_RANGE {
+			return nil, fmt.Errorf("predicate %d: unknown predicate %d", j, pred)
+		}
+
+		a, b, err := operands(c, field, values[col], h, op, j, pred)
+
+		if err != nil {
+			return nil, fmt.Errorf("predicate %d: %w", j, err)
+		}
+
+		leaves[j] = leafPredicate{col: col, pred: pred, a: a, b: b}
+	}
+
+	joins := make([]int, nLeaves-1)
+
+	for node := range joins {
+		join, err := intValue(c.Joins(h, op)[node])
+
+		if err != nil {
+			return nil, fmt.Errorf("filter node %d: join: %w", node, err)
+		}
+
+		if join != lib.JOIN_AND && join != lib.JOIN_OR {
+			return nil, fmt.Errorf("filter node %d: unknown join", node)
+		}
+
+		joins[node] = join
+	}
+
+	nots := make([]bool, nNodes)
+
+	for node := range nots {
+		not, err := intValue(c.FilterNots[h][op][node])
+
+		if err != nil {
+			return nil, fmt.Errorf("filter node %d: not: %w", node, err)
+		}
+
+		if not != 0 && not != 1 {
+			return nil, fmt.Errorf("filter node %d: not flag must be 0 or 1", node)
+		}
+
+		nots[node] = not == 1
+	}
+
+	mask := make([]bool, c.Config.MaxRows)
+
+	nodeValues := make([]bool, nNodes)
+
+	for row := 0; row < nr; row++ {
+		for j, l := range leaves {
+			nodeValues[nLeaves-1+j] = predicate(l.pred, values[l.col][row], l.a, l.b)
+		}
+
+		// Children come later in heap order, so evaluate bottom-up
+		for node := nNodes - 1; node >= 0; node-- {
+			if node < nLeaves-1 {
+				left, right := nodeValues[2*node+1], nodeValues[2*node+2]
+
+				nodeValues[node] = left && right
+
+				if joins[node] == lib.JOIN_OR {
+					nodeValues[node] = left || right
+				}
+			}
+
+			if nots[node] {
+				nodeValues[node] = !nodeValues[node]
+			}
+		}
+
+		mask[row] = nodeValues[0]
+	}
+
+	return mask, nil
+}
+
+// operands returns the [a, b] operands of predicate leaf j as signed integers
+// An active leaf compares every cell of its column (0 past NR) with both through
+// lib.ValueLessThan, so each must be comparable with each cell; PRED_NONE zeroes them.
+func operands(c *circuit.SimpleVerifierCircuit, field *big.Int, column []*big.Int, h, op, j, pred int) (*big.Int, *big.Int, error) {
+	args := make([]*big.Int, 2)
+
+	for i := range args {
+		raw, err := bigValue(c.FilterArgs[h][op][j][i])
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("operand %d: %w", i, err)
+		}
+
+		args[i] = signedValue(raw, field)
+
+		if pred == lib.PRED_NONE {
+			continue
+		}
+
+		for _, x := range column {
+			if _, err := valueLessThan(x, args[i], field); err != nil {
+				return nil, nil, fmt.Errorf("operand %d: %w", i, err)
+			}
+		}
+	}
+
+	return args[0], args[1], nil
+}
+
+// valueLessThan mirrors lib.ValueLessThan on field elements: a < b is bit VALUE_BITS + 1
+// of d = b - a + 2^(VALUE_BITS+1) - 1 (mod field). The circuit decomposes d on
+// VALUE_BITS + 2 bits, so b - a outside [1 - 2^(VALUE_BITS+1), 2^(VALUE_BITS+1)] is an error.
+func valueLessThan(a, b, field *big.Int) (bool, error) {
+	bits := uint(lib.VALUE_BITS + 1)
+
+	d := new(big.Int).Sub(b, a)
+
+	d.Add(d, new(big.Int).Lsh(big.NewInt(1), bits))
+
+	d.Sub(d, big.NewInt(1)).Mod(d, field)
+
+	if d.BitLen() > int(bits)+1 {
+		return false, fmt.Errorf("%s and %s are too far apart to compare (lib.ValueLessThan)", a, b)
+	}
+
+	return d.Bit(int(bits)) == 1, nil
+}
+
+// predicate evaluates a known leaf predicate (lib.PredicateValues) on a decoded value
+// Operands passed the valueLessThan checks, so integer comparisons match the circuit's.
+func predicate(pred int, x, a, b *big.Int) bool {
+	switch pred {
+	case lib.PRED_EQ:
+		return x.Cmp(a) == 0
+	case lib.PRED_NE:
+		return x.Cmp(a) != 0
+	case lib.PRED_LT:
+		return x.Cmp(a) < 0
+	case lib.PRED_LE:
+		return x.Cmp(a) <= 0
+	case lib.PRED_GT:
+		return x.Cmp(a) > 0
+	case lib.PRED_GE:
+		return x.Cmp(a) >= 0
+	case lib.PRED_RANGE:
+		return x.Cmp(a) >= 0 && x.Cmp(b) < 0
+	}
+
+	return true
+}
+
+// isEmptyFilter reports whether the filter of [h][op] selects every row by construction
+func isEmptyFilter(c *circuit.SimpleVerifierCircuit, h, op int) (bool, error) {
+	nodes := [][]frontend.Variable{c.FilterOps[h][op], c.Joins(h, op), c.FilterNots[h][op]}
+
+	// PRED_NONE leaves, JOIN_AND joins and no NOT are all 0
+	for _, values := range nodes {
+		for _, v := range values {
+			x, err := bigValue(v)
+
+			if err != nil {
+				return false, fmt.Errorf("filter: %w", err)
+			}
+
+			if x.Sign() != 0 {
+				return false, nil
+			}
+		}
+	}
+
+	return true, nil
+}
+
+// checkMerkleFilters checks the filtered MERKLE16 ops against cfg.MaxMerkleFilters
+// A plan counts its Filtered ops, the generic circuit the active ops with a non-empty filter.
+func checkMerkleFilters(c *circuit.SimpleVerifierCircuit, numHandlers int) error {
+	if c.Plan != nil {
+		if err := c.Plan.Validate(c.Config); err != nil {
+			return fmt.Errorf("native: %w", err)
+		}
+
+		return nil
+	}
+
+	count := 0
+
+	for h := 0; h < numHandlers && h < c.Config.MaxHandlers; h++ {
+		for op := 0; op < c.Config.MaxOps; op++ {
+			opCode, err := intValue(c.OpCodes[h][op])
+
+			if err != nil {
+				return fmt.Errorf("native: handler %d op %d: opcode: %w", h, op, err)
+			}
+
+			empty, err := isEmptyFilter(c, h, op)
+
+			if err != nil {
+				return fmt.Errorf("native: handler %d op %d: %w", h, op, err)
+			}
+
+			if opCode == lib.OP_MERKLE16 && !empty {
+				count++
+			}
+		}
+	}
+
+	if count > c.Config.MaxMerkleFilters {
+		return fmt.Errorf("native: %d filtered MERKLE16 ops, shape allows %d", count, c.Config.MaxMerkleFilters)
+	}
+
+	return nil
+}
+
+// merkleRoot returns the MERKLE16 root of handler h: raw cells of the rows in mask
+// in columns [start, start + nc), every other cell zeroed (operators.Merkle16Columns)
+func merkleRoot(c *circuit.SimpleVerifierCircuit, curve ecc.ID, mask []bool, h int) (*big.Int, error) {
+	cfg := c.Config
+
+	start, err := intValue(c.HandlerStartIndex[h])
+
+	if err != nil {
+		return nil, fmt.Errorf("HandlerStartIndex: %w", err)
+	}
+
+	nc, err := intValue(c.HandlerNCs[h])
+
+	if err != nil {
+		return nil, fmt.Errorf("HandlerNCs: %w", err)
+	}
+
+	end := start + nc
+
+	flat := make([]*big.Int, cfg.TotalItems())
+
+	for col := 0; col < cfg.MaxCols; col++ {
+		for row := 0; row < cfg.MaxRows; row++ {
+			flat[col*cfg.MaxRows+row] = big.NewInt(0)
+
+			if !mask[row] || col < start || col >= end {
+				continue
+			}
+
+			cell, err := bigValue(c.Items[col][row])
+
+			if err != nil {
+				return nil, fmt.Errorf("row %d column %d: %w", row, col, err)
+			}
+
+			flat[col*cfg.MaxRows+row] = cell
+		}
+	}
+
+	return Merkle16Root(curve, flat, cfg.NLevels()), nil
+}
+
+// countRows returns the number of selected rows
+func countRows(mask []bool) int {
+	count := 0
+
+	for _, selected := range mask {
+		if selected {
+			count++
+		}
+	}
+
+	return count
+}
+
+// sumRows sums the selected values
+func sumRows(column []*big.Int, mask []bool) *big.Int {
+	sum := big.NewInt(0)
+
+	for row, selected := range mask {
+		if selected {
+			sum.Add(sum, column[row])
+		}
+	}
+
+	return sum
+}
+
+// minMaxRows returns the minimum and maximum of the selected values (0, 0 if none)
+func minMaxRows(column []*big.Int, mask []bool) (*big.Int, *big.Int) {
+	minValue, maxValue := big.NewInt(0), big.NewInt(0)
+
+	seen := false
+
+	for row, selected := range mask {
+		if !selected {
+			continue
+		}
+
+		if !seen || column[row].Cmp(minValue) < 0 {
+			minValue = column[row]
+		}
+
+		if !seen || column[row].Cmp(maxValue) > 0 {
+			maxValue = column[row]
+		}
+
+		seen = true
+	}
+
+	return new(big.Int).Set(minValue), new(big.Int).Set(maxValue)
+}
+
+// signedValue reads a field element as a signed integer (above p/2 is negative)
+func signedValue(v *big.Int, field *big.Int) *big.Int {
+	x := new(big.Int).Mod(v, field)
+
+	if x.Cmp(new(big.Int).Rsh(field, 1)) > 0 {
+		x.Sub(x, field)
+	}
+
+	return x
+}
+
+// isGroupBy reports whether opCode is a GROUP BY op (reads GroupKeys)
+func isGroupBy(opCode int) bool {
+	switch opCode {
+	case lib.OP_SUM_COL_BY, lib.OP_MIN_COL_BY, lib.OP_MAX_COL_BY, lib.OP_COUNT_BY:
+		return true
+	}
+
+	return false
+}
+
+// planOp returns the plan op of a slot (OP_NOOP outside the plan)
+func planOp(plan *circuit.Plan, h, op int) circuit.PlanOp {
+	if h >= len(plan.Handlers) || op >= len(plan.Handlers[h]) {
+		return circuit.PlanOp{OpCode: lib.OP_NOOP}
+	}
+
+	return plan.Handlers[h][op]
+}
+
+// bigValue returns an assignment value as a big integer
+// Accepts ints, *big.Int, decimal strings and fr.Element (values or pointers)
+func bigValue(v frontend.Variable) (*big.Int, error) {
+	switch x := v.(type) {
+	case *big.Int:
+		return new(big.Int).Set(x), nil
+	case big.Int:
+		return new(big.Int).Set(&x), nil
+	case int:
+		return big.NewInt(int64(x)), nil
+	case int64:
+		return big.NewInt(x), nil
+	case uint64:
+		return new(big.Int).SetUint64(x), nil
+	case string:
+		value, ok := new(big.Int).SetString(x, 0)
+
+		if !ok {
+			return nil, fmt.Errorf("invalid integer %q", x)
+		}
+
+		return value, nil
+	case fr_bn254.Element:
+		return x.BigInt(new(big.Int)), nil
+	case fr_bls12381.Element:
+		return x.BigInt(new(big.Int)), nil
+	case fr_bls12377.Element:
+		return x.BigInt(new(big.Int)), nil
+	case fr_bw6761.Element:
+		return x.BigInt(new(big.Int)), nil
+	case interface{ BigInt(*big.Int) *big.Int }:
+		return x.BigInt(new(big.Int)), nil
+	}
+
+	return nil, fmt.Errorf("unsupported assignment value %T", v)
+}
+
+// intValue returns an index, code, count or width of an assignment as an int
+func intValue(v frontend.Variable) (int, error) {
+	x, err := bigValue(v)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if !x.IsInt64() || x.Int64() < math.MinInt32 || x.Int64() > math.MaxInt32 {
+		return 0, fmt.Errorf("%s out of range", x)
+	}
+
+	return int(x.Int64()), nil
+}
@@ -0,0 +1,323 @@
+package native_test
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"simple-verifier-gnark/internal/sample"
+	"simple-verifier-gnark/pkg/circuit"
+	"simple-verifier-gnark/pkg/lib"
+	"simple-verifier-gnark/pkg/native"
+	"simple-verifier-gnark/pkg/witness"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/test"
+)
+
+// everyOpCode adds every opcode to b: delta (column 3) runs from 5 down to -13
+func everyOpCode(b *witness.Builder) {
+	keys := witness.Ints(0, 1, 2)
+
+	h := b.AddHandler(0, 4)
+
+	b.AddOp(h, lib.OP_MERKLE16)
+
+	b.AddOp(h, lib.OP_COUNT)
+
+	b.AddOp(h, lib.OP_SUM_COL, 1)
+
+	b.AddOp(h, lib.OP_AVG_COL, 3)
+
+	// MIN_COL_BY reports negative minima before MAX_COL and MIN_COL read the same column
+	h = b.AddHandler(0, 4)
+
+	op := b.AddOp(h, lib.OP_MIN_COL_BY, 3, 2)
+
+	b.SetGroups(h, op, keys...)
+
+	b.AddOp(h, lib.OP_MAX_COL, 3)
+
+	b.AddOp(h, lib.OP_MIN_COL, 3)
+
+	b.AddOp(h, lib.OP_SUM_PRODUCT, 1, 3)
+
+	h = b.AddHandler(1, 2)
+
+	op = b.AddOp(h, lib.OP_SUM_COL_BY, 3, 2)
+
+	b.SetGroups(h, op, keys...)
+
+	op = b.AddOp(h, lib.OP_MAX_COL_BY, 3, 2)
+
+	b.SetGroups(h, op, keys...)
+
+	op = b.AddOp(h, lib.OP_COUNT_BY, 0, 2)
+
+	b.SetGroups(h, op, keys...)
+
+	op = b.AddOp(h, lib.OP_MERKLE16)
+
+	b.SetFilter(h, op, &circuit.Filter{Pred: lib.PRED_LT, Col: 3, A: big.NewInt(0)})
+}
+
+func TestResultsMatchCircuit(t *testing.T) {
+	curve := ecc.BN254
+
+	field := curve.ScalarField()
+
+	b := sample.Dataset(sample.Shape())
+
+	everyOpCode(b)
+
+	assignment, err := b.Assignment(curve)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := native.Results(assignment, curve)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fe := func(v int64) *big.Int {
+		return new(big.Int).Mod(big.NewInt(v), field)
+	}
+
+	want := []struct {
+		name       string
+		h, op, pos int
+		value      *big.Int
+	}{
+		{"COUNT", 0, 1, 0, fe(10)},
+		{"SUM_COL", 0, 2, 0, fe(145)},
+		{"AVG_COL quotient", 0, 3, 0, fe(-4)},
+		{"AVG_COL remainder", 0, 3, 1, fe(0)},
+		{"MIN_COL_BY", 1, 0, 0, fe(-13)},
+		{"MIN_COL_BY", 1, 0, 1, fe(-9)},
+		{"MIN_COL_BY", 1, 0, 2, fe(-11)},
+		{"MAX_COL after MIN_COL_BY", 1, 1, 0, fe(5)},
+		{"MIN_COL after MIN_COL_BY", 1, 2, 0, fe(-13)},
+		{"SUM_COL_BY", 2, 0, 0, fe(-16)},
+		{"MAX_COL_BY", 2, 1, 1, fe(3)},
+		{"COUNT_BY", 2, 2, 0, fe(4)},
+	}
+
+	for _, w := range want {
+		if got := results[w.h][w.op][w.pos]; got.Cmp(w.value) != 0 {
+			t.Errorf("%s [%d][%d][%d] = %s, want %s", w.name, w.h, w.op, w.pos, got, w.value)
+		}
+	}
+
+	if err := test.IsSolved(circuit.New(sample.Shape()), assignment, field); err != nil {
+		t.Fatalf("native results rejected by the circuit: %v", err)
+	}
+
+	// Every op slot is checked: a result off by one is rejected
+	for h := range results {
+		for op := range results[h] {
+			assignment.Results[h][op][0] = new(big.Int).Add(results[h][op][0], big.NewInt(1))
+
+			if err := test.IsSolved(circuit.New(sample.Shape()), assignment, field); err == nil {
+				t.Errorf("handler %d op %d: forged result accepted", h, op)
+			}
+
+			assignment.Results[h][op][0] = results[h][op][0]
+		}
+	}
+}
+
+func TestResultsErrors(t *testing.T) {
+	curve := ecc.BN254
+
+	field := curve.ScalarField()
+
+	pow := func(n uint) *big.Int {
+		return new(big.Int).Lsh(big.NewInt(1), n)
+	}
+
+	cases := []struct {
+		name   string
+		tamper func(c *circuit.SimpleVerifierCircuit)
+		want   string
+	}{
+		{"unsupported value", func(c *circuit.SimpleVerifierCircuit) {
+			c.NR = 1.5
+		}, "unsupported assignment value float64"},
+		{"invalid string", func(c *circuit.SimpleVerifierCircuit) {
+			c.Items[1][0] = "ten"
+		}, `invalid integer "ten"`},
+		{"huge opcode", func(c *circuit.SimpleVerifierCircuit) {
+			c.OpCodes[0][1] = pow(70)
+		}, "opcode"},
+		{"operand too far from the cells", func(c *circuit.SimpleVerifierCircuit) {
+			c.FilterArgs[2][3][0][0] = pow(66)
+		}, "too far apart to compare"},
+		{"operand too far below the cells", func(c *circuit.SimpleVerifierCircuit) {
+			c.FilterArgs[2][3][0][0] = new(big.Int).Sub(field, pow(66))
+		}, "too far apart to compare"},
+		{"unused operand of an active leaf", func(c *circuit.SimpleVerifierCircuit) {
+			c.FilterArgs[2][3][0][1] = pow(100)
+		}, "too far apart to compare"},
+		{"filter column out of range", func(c *circuit.SimpleVerifierCircuit) {
+			c.FilterCols[2][3][0] = 4
+		}, "predicate 0: column 4 out of range"},
+		{"column arg out of range", func(c *circuit.SimpleVerifierCircuit) {
+			c.OpArgs[0][2][1] = -1
+		}, "column args (1, -1) out of range"},
+		{"group keys too far apart", func(c *circuit.SimpleVerifierCircuit) {
+			c.GroupKeys[1][0][2] = pow(66)
+		}, "group key 2: 1 and"},
+		{"group keys decreasing", func(c *circuit.SimpleVerifierCircuit) {
+			c.GroupKeys[1][0][0] = 1
+		}, "group keys must be strictly increasing"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			b := sample.Dataset(sample.Shape())
+
+			everyOpCode(b)
+
+			assignment, err := b.Assignment(curve)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tc.tamper(assignment)
+
+			_, err = native.Results(assignment, curve)
+
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("error %v, want %q", err, tc.want)
+			}
+		})
+	}
+
+	// Operands of PRED_NONE leaves are zeroed by the circuit and not checked
+	b := sample.Dataset(sample.Shape())
+
+	everyOpCode(b)
+
+	assignment, err := b.Assignment(curve)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assignment.FilterArgs[0][1][0][0] = pow(100)
+
+	if _, err := native.Results(assignment, curve); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestResultsAgreeWithCircuit checks that Evaluate accepts exactly the inputs the
+// generic and the specialized circuit accept
+func TestResultsAgreeWithCircuit(t *testing.T) {
+	curve := ecc.BN254
+
+	field := curve.ScalarField()
+
+	pow := func(n uint) *big.Int {
+		return new(big.Int).Lsh(big.NewInt(1), n)
+	}
+
+	// COUNT, SUM(delta) GROUP BY key, SUM(delta) WHERE delta < 0
+	plan := circuit.Plan{Handlers: [][]circuit.PlanOp{{
+		{OpCode: lib.OP_COUNT},
+		{OpCode: lib.OP_SUM_COL_BY, ColX: 3, ColY: 2},
+		{OpCode: lib.OP_SUM_COL, ColX: 3, Filtered: true},
+	}}}
+
+	// setLeaf sets the filter column and first operand of the WHERE of op 2
+	setLeaf := func(col int, a *big.Int) func(c *circuit.SimpleVerifierCircuit) {
+		return func(c *circuit.SimpleVerifierCircuit) {
+			for p, pred := range c.FilterOps[0][2] {
+				if pred == lib.PRED_LT {
+					c.FilterCols[0][2][p], c.FilterArgs[0][2][p][0] = col, a
+				}
+			}
+		}
+	}
+
+	// setKeys replaces the group keys of op
+	setKeys := func(op int, keys ...*big.Int) func(c *circuit.SimpleVerifierCircuit) {
+		return func(c *circuit.SimpleVerifierCircuit) {
+			c.NumGroups[0][op] = len(keys)
+
+			for g, key := range keys {
+				c.GroupKeys[0][op][g] = key
+			}
+		}
+	}
+
+	cases := []struct {
+		name                 string
+		tamper               func(c *circuit.SimpleVerifierCircuit)
+		generic, specialized bool
+	}{
+		{"untampered", func(c *circuit.SimpleVerifierCircuit) {}, true, true},
+
+		// Operands only need to be comparable with every cell: 2^64 and -2^64 are
+		{"operand 2^64", setLeaf(3, pow(64)), true, true},
+		{"operand -2^64", setLeaf(3, new(big.Int).Sub(field, pow(64))), true, true},
+		{"operand 2^66", setLeaf(3, pow(66)), false, false},
+		{"filter column out of range", setLeaf(4, big.NewInt(0)), false, false},
+
+		// An unused key may lie outside the cell range, but not too far from the previous one
+		{"unused key 2^64", setKeys(1, big.NewInt(0), big.NewInt(1), big.NewInt(2), pow(64)), true, true},
+		{"unused key 2^66", setKeys(1, big.NewInt(0), big.NewInt(1), big.NewInt(2), pow(66)), false, false},
+
+		// A plan only checks the group keys of its GROUP BY ops and the filters of its filtered ops
+		{"group keys of COUNT", setKeys(0, big.NewInt(7)), false, true},
+		{"filter column of an unfiltered op", func(c *circuit.SimpleVerifierCircuit) {
+			c.FilterCols[0][0][0] = 9
+		}, false, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, specialized := range []bool{false, true} {
+				b := sample.Dataset(sample.Shape())
+
+				h := b.AddHandler(0, 4)
+
+				b.AddOp(h, lib.OP_COUNT)
+
+				b.SetGroups(h, b.AddOp(h, lib.OP_SUM_COL_BY, 3, 2), witness.Ints(0, 1, 2)...)
+
+				b.SetFilter(h, b.AddOp(h, lib.OP_SUM_COL, 3), &circuit.Filter{Pred: lib.PRED_LT, Col: 3, A: big.NewInt(0)})
+
+				assignment, err := b.Assignment(curve)
+
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				c, want := circuit.New(sample.Shape()), tc.generic
+
+				if specialized {
+					c, want = circuit.NewSpecialized(sample.Shape(), plan), tc.specialized
+
+					assignment.Plan = &plan
+				}
+
+				tc.tamper(assignment)
+
+				err = native.Evaluate(assignment, curve)
+
+				if (err == nil) != want {
+					t.Errorf("specialized %v: native error %v, want accepted %v", specialized, err, want)
+				}
+
+				if err := test.IsSolved(c, assignment, field); (err == nil) != want {
+					t.Errorf("specialized %v: circuit error %v, want accepted %v", specialized, err, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package native
+
+import (
+	"fmt"
+	"math/big"
+
+	"simple-verifier-gnark/pkg/circuit"
+	"simple-verifier-gnark/pkg/lib"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	poseidon2_bls12377 "github.com/consensys/gnark-crypto/ecc/bls12-377/fr/poseidon2"
+	poseidon2_bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381/fr/poseidon2"
+	poseidon2_bn254 "github.com/consensys/gnark-crypto/ecc/bn254/fr/poseidon2"
+	poseidon2_bw6761 "github.com/consensys/gnark-crypto/ecc/bw6-761/fr/poseidon2"
+	gnarkhash "github.com/consensys/gnark-crypto/hash"
+)
+
+// NewPoseidon2Hasher returns the off-chain Poseidon2 hasher of curve
+// Rounds come from lib.Curves so they match the in-circuit hasher
+func NewPoseidon2Hasher(curve ecc.ID) gnarkhash.StateStorer {
+	rounds, err := lib.Poseidon2RoundsOf(curve)
+
+	if err != nil {
+		panic(err)
+	}
+
+	var perm gnarkhash.Compressor
+
+	switch curve {
+	case ecc.BLS12_381:
+		perm = poseidon2_bls12381.NewPermutation(lib.Poseidon2Width, rounds.Full, rounds.Partial)
+	case ecc.BLS12_377:
+		perm = poseidon2_bls12377.NewPermutation(lib.Poseidon2Width, rounds.Full, rounds.Partial)
+	case ecc.BW6_761:
+		perm = poseidon2_bw6761.NewPermutation(lib.Poseidon2Width, rounds.Full, rounds.Partial)
+	default:
+		perm = poseidon2_bn254.NewPermutation(lib.Poseidon2Width, rounds.Full, rounds.Partial)
+	}
+
+	return gnarkhash.NewMerkleDamgardHasher(perm, make([]byte, perm.BlockSize()))
+}
+
+// Poseidon2 hashes field elements of curve (inputs are reduced modulo its scalar field)
+// Same as lib.NewPoseidon2Hasher in circuit; two inputs match lib.Poseidon2Two
+func Poseidon2(curve ecc.ID, inputs ...*big.Int) *big.Int {
+	h := NewPoseidon2Hasher(curve)
+
+	modulus := curve.ScalarField()
+
+	for _, input := range inputs {
+		elem := new(big.Int).Mod(input, modulus)
+
+		h.Write(elem.FillBytes(make([]byte, h.BlockSize())))
+	}
+
+	return new(big.Int).SetBytes(h.Sum(nil))
+}
+
+// Merkle16Root pads items with zeros to 16^nLevels leaves (as operators.Merkle16Ordered)
+func Merkle16Root(curve ecc.ID, items []*big.Int, nLevels int) *big.Int {
+	totalItems := 1
+
+	for level := 0; level < nLevels; level++ {
+		totalItems *= 16
+	}
+
+	currentLevel := make([]*big.Int, totalItems)
+
+	copy(currentLevel, items)
+
+	for i := len(items); i < totalItems; i++ {
+		currentLevel[i] = big.NewInt(0)
+	}
+
+	for level := 0; level < nLevels; level++ {
+		nextLevel := make([]*big.Int, len(currentLevel)/16)
+
+		for i := range nextLevel {
+			nextLevel[i] = Poseidon2(curve, currentLevel[i*16:(i+1)*16]...)
+		}
+
+		currentLevel = nextLevel
+	}
+
+	return currentLevel[0]
+}
+
+// DataRoot returns the commitment of an assignment (as operators.DataCommitment)
+// Poseidon2(Merkle16Ordered(Items), NR) over every cell, padding rows included
+func DataRoot(c *circuit.SimpleVerifierCircuit, curve ecc.ID) (*big.Int, error) {
+	cfg := c.Config
+
+	flat := make([]*big.Int, cfg.TotalItems())
+
+	for col := 0; col < cfg.MaxCols; col++ {
+		for row := 0; row < cfg.MaxRows; row++ {
+			cell, err := bigValue(c.Items[col][row])
+
+			if err != nil {
+				return nil, fmt.Errorf("native: row %d column %d: %w", row, col, err)
+			}
+
+			flat[col*cfg.MaxRows+row] = cell
+		}
+	}
+
+	nr, err := bigValue(c.NR)
+
+	if err != nil {
+		return nil, fmt.Errorf("native: NR: %w", err)
+	}
+
+	return Poseidon2(curve, Merkle16Root(curve, flat, cfg.NLevels()), nr), nil
+}
+
+// SSZEncode hashes values to a binary Merkle root, zero padded to a power of 2 (as lib.SSZEncode)
+func SSZEncode(curve ecc.ID, values []*big.Int) *big.Int {
+	size := 2
+
+	for size < len(values) {
+		size *= 2
+	}
+
+	level := make([]*big.Int, size)
+
+	for i := range level {
+		level[i] = big.NewInt(0)
+
+		if i < len(values) {
+			level[i] = values[i]
+		}
+	}
+
+	for len(level) > 1 {
+		next := make([]*big.Int, len(level)/2)
+
+		for i := range next {
+			next[i] = Poseidon2(curve, level[2*i], level[2*i+1])
+		}
+
+		level = next
+	}
+
+	return level[0]
+}
+
+// SSZKeyValue hashes each (key, value) pair, then SSZEncodes the pair hashes (as lib.SSZKeyValue)
+func SSZKeyValue(curve ecc.ID, keys, values []*big.Int) *big.Int {
+	pairs := make([]*big.Int, len(keys))
+
+	for i := range keys {
+		pairs[i] = Poseidon2(curve, keys[i], values[i])
+	}
+
+	return SSZEncode(curve, pairs)
+}
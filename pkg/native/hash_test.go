@@ -0,0 +1,74 @@
+package native_test
+
+import (
+	"math/big"
+	"sort"
+	"testing"
+
+	"simple-verifier-gnark/internal/sample"
+	"simple-verifier-gnark/pkg/circuit"
+	"simple-verifier-gnark/pkg/lib"
+	"simple-verifier-gnark/pkg/native"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/test"
+)
+
+func TestRootsMatchCircuitOnEveryCurve(t *testing.T) {
+	curves := make([]ecc.ID, 0, len(lib.Curves))
+
+	for curve := range lib.Curves {
+		curves = append(curves, curve)
+	}
+
+	sort.Slice(curves, func(i, k int) bool { return curves[i] < curves[k] })
+
+	cfg := sample.Shape()
+
+	for _, curve := range curves {
+		t.Run(curve.String(), func(t *testing.T) {
+			b := sample.Dataset(cfg)
+
+			h := b.AddHandler(0, cfg.MaxCols)
+
+			merkle := b.AddOp(h, lib.OP_MERKLE16)
+
+			assignment, err := b.Assignment(curve)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			root, err := native.DataRoot(assignment, curve)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if root.Cmp(assignment.DataRoot.(*big.Int)) != 0 {
+				t.Fatalf("DataRoot %s, native %s", assignment.DataRoot, root)
+			}
+
+			// The circuit recomputes DataRoot and the MERKLE16 root with the curve's Poseidon2
+			field := curve.ScalarField()
+
+			if err := test.IsSolved(circuit.New(cfg), assignment, field); err != nil {
+				t.Fatalf("native roots rejected: %v", err)
+			}
+
+			result := assignment.Results[h][merkle][0].(*big.Int)
+
+			assignment.Results[h][merkle][0] = new(big.Int).Add(result, big.NewInt(1))
+
+			if err := test.IsSolved(circuit.New(cfg), assignment, field); err == nil {
+				t.Error("forged MERKLE16 root accepted")
+			}
+
+			assignment.Results[h][merkle][0], assignment.DataRoot = result, new(big.Int).Add(root, big.NewInt(1))
+
+			if err := test.IsSolved(circuit.New(cfg), assignment, field); err == nil {
+				t.Error("forged DataRoot accepted")
+			}
+		})
+	}
+}
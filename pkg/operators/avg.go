@@ -0,0 +1,31 @@
+package operators
+
+import (
+	"simple-verifier-gnark/pkg/lib"
+
+	"github.com/consensys/gnark/frontend"
+)
+
+// AverageResult holds the outputs of Average
+type AverageResult struct {
+	Quotient  frontend.Variable
+	Remainder frontend.Variable
+}
+
+// Average proves floor(sum / count) as an exact quotient and remainder
+// sum == Quotient*count + Remainder, Remainder < count
+//
+// count == 0 yields Quotient = Remainder = 0 (sum must then be 0).
+// sum is read as a signed integer with |sum| <= 2^(VALUE_BITS + COUNT_BITS),
+// so negative averages of signed columns round toward -infinity.
+func Average(api frontend.API, sum frontend.Variable, count frontend.Variable) AverageResult {
+	// Divide by 1 instead of 0 for empty selections
+	divisor := api.Add(count, api.IsZero(count))
+
+	q, r := lib.DivMod(api, sum, divisor, lib.VALUE_BITS+lib.COUNT_BITS, lib.COUNT_BITS)
+
+	return AverageResult{
+		Quotient:  q,
+		Remainder: r,
+	}
+}
@@ -0,0 +1,61 @@
+package operators
+
+import (
+	"testing"
+
+	"simple-verifier-gnark/internal/testutil"
+
+	"github.com/consensys/gnark/frontend"
+)
+
+// averageCircuit checks Average against public expected results
+type averageCircuit struct {
+	Sum       frontend.Variable
+	Count     frontend.Variable
+	Quotient  frontend.Variable `gnark:",public"`
+	Remainder frontend.Variable `gnark:",public"`
+}
+
+func (c *averageCircuit) Define(api frontend.API) error {
+	res := Average(api, c.Sum, c.Count)
+
+	api.AssertIsEqual(res.Quotient, c.Quotient)
+
+	api.AssertIsEqual(res.Remainder, c.Remainder)
+
+	return nil
+}
+
+func TestAverage(t *testing.T) {
+	cases := []struct {
+		name       string
+		sum, count any
+		q, r       any
+		want       bool
+	}{
+		{"exact", 12, 4, 3, 0, true},
+		{"remainder", 10, 3, 3, 1, true},
+		{"negative sum rounds down", fe(-7), 2, fe(-4), 1, true},
+		{"empty selection", 0, 0, 0, 0, true},
+		{"wrong quotient", 10, 3, 4, 1, false},
+		{"wrong remainder", 10, 3, 3, 2, false},
+		{"truncated negative", fe(-7), 2, fe(-3), 1, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assignment := &averageCircuit{Sum: tc.sum, Count: tc.count, Quotient: tc.q, Remainder: tc.r}
+
+			testutil.AssertSolved(t, &averageCircuit{}, assignment, tc.want)
+		})
+	}
+}
+
+func TestAverageRejectsForgedQuotient(t *testing.T) {
+	// 10 = 4*3 - 2 = 2*3 + 4: any other quotient needs r < 0 or r >= count
+	for _, k := range []int64{1, 2, -1} {
+		assignment := &averageCircuit{Sum: 10, Count: 3, Quotient: fe(3 + k), Remainder: fe(1 - 3*k)}
+
+		testutil.AssertSolved(t, &averageCircuit{}, assignment, false, forgeDivMod(k))
+	}
+}
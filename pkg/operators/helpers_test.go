@@ -0,0 +1,25 @@
+package operators
+
+import (
+	"math/big"
+
+	"simple-verifier-gnark/internal/testutil"
+	"simple-verifier-gnark/pkg/lib"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/constraint/solver"
+)
+
+// forgeDivMod shifts the honest quotient by k: q + k, r - k*b (a == q*b + r still holds)
+func forgeDivMod(k int64) solver.Option {
+	return testutil.ForgeHint(lib.DivModHint, func(field *big.Int, inputs, outputs []*big.Int) {
+		outputs[0].Add(outputs[0], big.NewInt(k)).Mod(outputs[0], field)
+
+		outputs[1].Sub(outputs[1], new(big.Int).Mul(big.NewInt(k), inputs[1])).Mod(outputs[1], field)
+	})
+}
+
+// fe returns v as a BN254 field element (negatives wrap to p - |v|)
+func fe(v int64) *big.Int {
+	return new(big.Int).Mod(big.NewInt(v), ecc.BN254.ScalarField())
+}
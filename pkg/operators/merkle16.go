@@ -8,14 +8,25 @@ import (
 
 // Merkle16Ordered builds a 16-ary Merkle tree from all items
 // Port of circom Merkle16Ordered template
+//
+// Items are padded with zeros up to 16^nLevels leaves (see lib.MerkleLevels)
 func Merkle16Ordered(api frontend.API, items []frontend.Variable, nLevels int) frontend.Variable {
 	branchingFactor := 16
-	totalItems := len(items)
+
+	totalItems := 1
+
+	for level := 0; level < nLevels; level++ {
+		totalItems *= branchingFactor
+	}
 
 	currentLevel := make([]frontend.Variable, totalItems)
 
 	copy(currentLevel, items)
 
+	for i := len(items); i < totalItems; i++ {
+		currentLevel[i] = frontend.Variable(0)
+	}
+
 	for level := 0; level < nLevels; level++ {
 		levelSize := len(currentLevel)
 		nextLevelSize := levelSize / branchingFactor
@@ -45,3 +56,169 @@ func Merkle16OrderedWithMask(api frontend.API, items []frontend.Variable, mask [
 
 	return Merkle16Ordered(api, maskedItems, nLevels)
 }
+
+// DataCommitment binds the full items matrix and the row count to one root
+// root = Poseidon2(Merkle16Ordered(items), NR)
+//
+// Unlike Merkle16OrderedWithMask, no mask is applied: every cell (including
+// padding rows) is committed, so the prover cannot swap the dataset.
+func DataCommitment(api frontend.API, items []frontend.Variable, NR frontend.Variable, nLevels int) frontend.Variable {
+	itemsRoot := Merkle16Ordered(api, items, nLevels)
+
+	return lib.Poseidon2Two(api, itemsRoot, NR)
+}
+
+// Merkle16Columns holds the single-column nodes of the 16-ary Merkle tree of a row-masked
+// items matrix [col][row], laid out column by column (flatItems[col*nRows + row]).
+//
+// Built once, it derives the root of any column-masked variant of the same rows
+// (see Root): subtrees inside a single column are reused as is or replaced by
+// the all-zero subtree, only subtrees spanning several columns are re-hashed.
+type Merkle16Columns struct {
+	// nodes[level][i]: node i of the tree (level 0 = masked leaves)
+	// nil for nodes spanning several columns
+	nodes [][]frontend.Variable
+
+	// zeros[level]: root of an all-zero subtree of that level
+	zeros []frontend.Variable
+
+	nRows  int
+	nItems int
+}
+
+// NewMerkle16Columns hashes items[col][row] * rowMask[row] into a tree of nLevels levels
+// Leaves beyond the matrix are padded with zeros (as Merkle16Ordered)
+func NewMerkle16Columns(api frontend.API, items [][]frontend.Variable, rowMask []frontend.Variable, nLevels int) *Merkle16Columns {
+	branchingFactor := 16
+
+	nRows := len(rowMask)
+
+	m := &Merkle16Columns{
+		nodes:  make([][]frontend.Variable, nLevels+1),
+		zeros:  make([]frontend.Variable, nLevels+1),
+		nRows:  nRows,
+		nItems: len(items) * nRows,
+	}
+
+	m.zeros[0] = frontend.Variable(0)
+
+	for level := 1; level <= nLevels; level++ {
+		var chunk [16]frontend.Variable
+
+		for j := 0; j < branchingFactor; j++ {
+			chunk[j] = m.zeros[level-1]
+		}
+
+		m.zeros[level] = lib.Poseidon2Chunk16(api, chunk)
+	}
+
+	levelSize := 1
+
+	for level := 0; level < nLevels; level++ {
+		levelSize *= branchingFactor
+	}
+
+	m.nodes[0] = make([]frontend.Variable, levelSize)
+
+	for i := m.nItems; i < levelSize; i++ {
+		m.nodes[0][i] = m.zeros[0]
+	}
+
+	for col := range items {
+		for row := 0; row < nRows; row++ {
+			idx := col*nRows + row
+
+			m.nodes[0][idx] = api.Mul(items[col][row], rowMask[row])
+		}
+	}
+
+	for level := 1; level <= nLevels; level++ {
+		levelSize /= branchingFactor
+
+		m.nodes[level] = make([]frontend.Variable, levelSize)
+
+		for i := 0; i < levelSize; i++ {
+			if m.padding(level, i) {
+				m.nodes[level][i] = m.zeros[level]
+
+				continue
+			}
+
+			// Nodes spanning several columns are re-hashed by every Root
+			if _, ok := m.column(level, i); !ok {
+				continue
+			}
+
+			var chunk [16]frontend.Variable
+
+			for j := 0; j < branchingFactor; j++ {
+				chunk[j] = m.nodes[level-1][i*branchingFactor+j]
+			}
+
+			m.nodes[level][i] = lib.Poseidon2Chunk16(api, chunk)
+		}
+	}
+
+	return m
+}
+
+// Root returns the tree root with column col zeroed wherever colMask[col] == 0
+// Equal to Merkle16OrderedWithMask(items, CreateFlatMask(rowMask, colMask))
+func (m *Merkle16Columns) Root(api frontend.API, colMask []frontend.Variable) frontend.Variable {
+	top := len(m.nodes) - 1
+
+	return m.maskedNode(api, colMask, top, 0)
+}
+
+// maskedNode returns node i of level with the column mask applied
+func (m *Merkle16Columns) maskedNode(api frontend.API, colMask []frontend.Variable, level, i int) frontend.Variable {
+	if m.padding(level, i) {
+		return m.zeros[level]
+	}
+
+	// Subtree inside one column: keep it or zero it as a whole
+	if col, ok := m.column(level, i); ok {
+		return api.Select(colMask[col], m.nodes[level][i], m.zeros[level])
+	}
+
+	var chunk [16]frontend.Variable
+
+	for j := 0; j < 16; j++ {
+		chunk[j] = m.maskedNode(api, colMask, level-1, i*16+j)
+	}
+
+	return lib.Poseidon2Chunk16(api, chunk)
+}
+
+// span returns the first leaf and the number of leaves under node i of level
+func (m *Merkle16Columns) span(level, i int) (int, int) {
+	size := 1
+
+	for l := 0; l < level; l++ {
+		size *= 16
+	}
+
+	return i * size, size
+}
+
+// padding reports whether node i of level only covers zero padding leaves
+func (m *Merkle16Columns) padding(level, i int) bool {
+	start, _ := m.span(level, i)
+
+	return start >= m.nItems
+}
+
+// column returns the column of node i of level if all its matrix leaves are in one column
+func (m *Merkle16Columns) column(level, i int) (int, bool) {
+	start, size := m.span(level, i)
+
+	last := start + size - 1
+
+	if last >= m.nItems {
+		last = m.nItems - 1
+	}
+
+	col := start / m.nRows
+
+	return col, last/m.nRows == col
+}
@@ -0,0 +1,57 @@
+package operators
+
+import (
+	"simple-verifier-gnark/pkg/lib"
+
+	"github.com/consensys/gnark/frontend"
+)
+
+// MinMaxColumnResult holds the outputs of MinMaxColumn
+type MinMaxColumnResult struct {
+	Min frontend.Variable
+	Max frontend.Variable
+}
+
+// MinMaxColumn finds the minimum and maximum of a column over masked rows
+//
+// Rows with mask[i] == 0 are ignored. Values are compared with lib.ValueLessThan,
+// so decoded signed columns order correctly.
+// If no row is valid, both Min and Max are 0.
+//
+// The column is extracted by sel (see lib.ColumnSelector)
+func MinMaxColumn(api frontend.API, sel lib.ColumnSelector, colIndex frontend.Variable, rowMask []frontend.Variable) MinMaxColumnResult {
+	column := sel.Column(api, colIndex)
+
+	minAccum := frontend.Variable(0)
+
+	maxAccum := frontend.Variable(0)
+
+	// seen = 1 once at least one valid row has been visited
+	seen := frontend.Variable(0)
+
+	for row := 0; row < len(rowMask); row++ {
+		maskedValue := api.Mul(column[row], rowMask[row])
+
+		// First valid row always replaces the accumulator, later rows only when smaller/larger
+		notSeen := api.Sub(1, seen)
+
+		isLess := lib.ValueLessThan(api, maskedValue, minAccum)
+
+		isGreater := lib.ValueLessThan(api, maxAccum, maskedValue)
+
+		takeMin := api.Mul(rowMask[row], api.Add(notSeen, api.Mul(seen, isLess)))
+
+		takeMax := api.Mul(rowMask[row], api.Add(notSeen, api.Mul(seen, isGreater)))
+
+		minAccum = api.Select(takeMin, maskedValue, minAccum)
+
+		maxAccum = api.Select(takeMax, maskedValue, maxAccum)
+
+		seen = api.Add(seen, api.Mul(notSeen, rowMask[row]))
+	}
+
+	return MinMaxColumnResult{
+		Min: minAccum,
+		Max: maxAccum,
+	}
+}
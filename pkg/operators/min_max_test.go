@@ -0,0 +1,70 @@
+package operators
+
+import (
+	"testing"
+
+	"simple-verifier-gnark/internal/testutil"
+	"simple-verifier-gnark/pkg/lib"
+
+	"github.com/consensys/gnark/frontend"
+)
+
+// minMaxCircuit checks MinMaxColumn on column Col of a 2x4 matrix
+type minMaxCircuit struct {
+	Items [2][4]frontend.Variable
+	Mask  [4]frontend.Variable
+	Col   frontend.Variable `gnark:",public"`
+	Min   frontend.Variable `gnark:",public"`
+	Max   frontend.Variable `gnark:",public"`
+}
+
+func (c *minMaxCircuit) Define(api frontend.API) error {
+	sel := lib.NewMuxSelector([][]frontend.Variable{c.Items[0][:], c.Items[1][:]})
+
+	res := MinMaxColumn(api, sel, c.Col, c.Mask[:])
+
+	api.AssertIsEqual(res.Min, c.Min)
+
+	api.AssertIsEqual(res.Max, c.Max)
+
+	return nil
+}
+
+// minMax returns an assignment over columns {7, 3, 9, 1} and {-5, 4, -2, 8}
+func minMax(mask [4]frontend.Variable, col, min, max any) *minMaxCircuit {
+	return &minMaxCircuit{
+		Items: [2][4]frontend.Variable{{7, 3, 9, 1}, {fe(-5), 4, fe(-2), 8}},
+		Mask:  mask,
+		Col:   col,
+		Min:   min,
+		Max:   max,
+	}
+}
+
+func TestMinMaxColumn(t *testing.T) {
+	all := [4]frontend.Variable{1, 1, 1, 1}
+
+	prefix := [4]frontend.Variable{1, 1, 1, 0}
+
+	cases := []struct {
+		name       string
+		assignment *minMaxCircuit
+		want       bool
+	}{
+		{"all rows", minMax(all, 0, 1, 9), true},
+		{"masked minimum", minMax(prefix, 0, 3, 9), true},
+		{"signed column", minMax(prefix, 1, fe(-5), 4), true},
+		{"filtered rows", minMax([4]frontend.Variable{0, 1, 1, 0}, 1, fe(-2), 4), true},
+		{"empty selection", minMax([4]frontend.Variable{0, 0, 0, 0}, 0, 0, 0), true},
+		{"masked row as minimum", minMax(prefix, 0, 1, 9), false},
+		{"value not in column", minMax(all, 0, 0, 9), false},
+		{"unsigned order of a signed column", minMax(prefix, 1, 4, fe(-5)), false},
+		{"wrong column", minMax(all, 1, 1, 9), false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			testutil.AssertSolved(t, &minMaxCircuit{}, tc.assignment, tc.want)
+		})
+	}
+}
@@ -4,99 +4,310 @@ import (
 	"simple-verifier-gnark/pkg/lib"
 
 	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/lookup/logderivlookup"
+	"github.com/consensys/gnark/std/multicommit"
 )
 
 // SumColumnByGroupResult holds the outputs of SumColumnByGroup
 type SumColumnByGroupResult struct {
-	GroupSums [lib.MAX_GROUPS]frontend.Variable
+	GroupSums   []frontend.Variable
+	GroupMins   []frontend.Variable
+	GroupMaxs   []frontend.Variable
+	GroupCounts []frontend.Variable
 }
 
 // SumColumnByGroup sums column X grouped by column Y
 // Port of circom SumColumnByGroup template
 //
+// Also tracks the per-group row count and the per-group minimum and
+// maximum of column X (empty groups = 0).
+// Extrema comparisons (lib.ValueLessThan) only run when extremaFlag == 1;
+// otherwise GroupMins/GroupMaxs are 0 (a constant 0 flag skips them at compile time).
+//
 // PUBLIC KEYS approach:
-//   - Group keys [A, B, C, ...] are PUBLIC input
+//   - Group keys [A, B, C, ...] are PUBLIC input, strictly increasing (lib.ValueLessThan)
 //   - Each row MUST match one of the public keys
 //   - If any row has unknown key => circuit FAILS
 //
+// LOG-DERIVATIVE argument: counts and sums come from a hint and are checked
+// against the rows at a random point r (std/multicommit):
+//
+//	sum_row mask/(r - y)     == sum_g count_g/(r - key_g)
+//	sum_row mask*x/(r - y)   == sum_g sum_g/(r - key_g)
+//
+// A row with an unknown key leaves a pole on the left side only, so the
+// identity also proves membership. Keys are distinct, so each pole is one group.
+// Extrema: every row reads its group's min/max from lookup tables (hinted index,
+// checked against the key), is bounded by them, and each non-empty group must
+// have at least one row equal to them (hit counts, same identity).
+// Cost grows with rows + groups instead of rows * groups.
+//
+// valuesX / valuesY are columns X and Y already selected from items
+// (see lib.ColumnSelector), so other operators can share the selection.
+//
 // Note: When numGroups = 0, validation is DISABLED (for non-SUM_BY ops)
 func SumColumnByGroup(
 	api frontend.API,
-	items [lib.MAX_COLS][lib.MAX_ROWS]frontend.Variable,
-	colX frontend.Variable,
-	colY frontend.Variable,
+	valuesX []frontend.Variable,
+	valuesY []frontend.Variable,
 	rowMask []frontend.Variable,
-	groupKeys [lib.MAX_GROUPS]frontend.Variable,
+	groupKeys []frontend.Variable,
 	numGroups frontend.Variable,
+	extremaFlag frontend.Variable,
 ) SumColumnByGroupResult {
+	nGroups := len(groupKeys)
+	nRows := len(rowMask)
+
 	// Create group mask: groupMask[g] = 1 if g < numGroups
-	groupMask := make([]frontend.Variable, lib.MAX_GROUPS)
+	groupMask := make([]frontend.Variable, nGroups)
 
-	for g := 0; g < lib.MAX_GROUPS; g++ {
-		groupMask[g] = lib.LessThan(api, frontend.Variable(g), numGroups, 8)
+	for g := 0; g < nGroups; g++ {
+		groupMask[g] = lib.LessThan(api, frontend.Variable(g), numGroups, lib.IndexBits(nGroups))
 	}
 
-	// Select column values
-	valuesX := make([]frontend.Variable, lib.MAX_ROWS)
+	enabled := api.Sub(1, api.IsZero(numGroups))
 
-	valuesY := make([]frontend.Variable, lib.MAX_ROWS)
+	// Active keys strictly increasing (inactive keys are compared as 0)
+	for g := 1; g < nGroups; g++ {
+		prevKey := api.Mul(groupKeys[g-1], groupMask[g])
 
-	for row := 0; row < lib.MAX_ROWS; row++ {
-		rowData := make([]frontend.Variable, lib.MAX_COLS)
+		key := api.Mul(groupKeys[g], groupMask[g])
 
-		for col := 0; col < lib.MAX_COLS; col++ {
-			rowData[col] = items[col][row]
-		}
+		isIncreasing := lib.ValueLessThan(api, prevKey, key)
+
+		api.AssertIsEqual(api.Mul(api.Sub(1, isIncreasing), groupMask[g]), 0)
+	}
+
+	withExtrema := true
 
-		valuesX[row] = lib.Selector(api, rowData, colX)
+	if flag, ok := api.Compiler().ConstantValue(extremaFlag); ok && flag.Sign() == 0 {
+		withExtrema = false
+	}
+
+	extrema := api.Mul(extremaFlag, enabled)
+
+	// Hint: per-group aggregates and the group index of every row
+	inputs := make([]frontend.Variable, 0, 2+3*nRows+2*nGroups)
+
+	inputs = append(inputs, extrema, nGroups)
+	inputs = append(inputs, valuesX...)
+	inputs = append(inputs, valuesY...)
+	inputs = append(inputs, rowMask...)
+	inputs = append(inputs, groupKeys...)
+	inputs = append(inputs, groupMask...)
+
+	res, err := api.Compiler().NewHint(lib.GroupByHint, 6*nGroups+nRows, inputs...)
 
-		valuesY[row] = lib.Selector(api, rowData, colY)
+	if err != nil {
+		panic("failed to create group by hint: " + err.Error())
 	}
 
-	// Initialize group sum accumulators
-	groupSumAccum := make([]frontend.Variable, lib.MAX_GROUPS)
+	// Inactive groups are forced to 0
+	groupCounts := make([]frontend.Variable, nGroups)
+
+	groupSums := make([]frontend.Variable, nGroups)
+
+	groupMins := make([]frontend.Variable, nGroups)
+
+	groupMaxs := make([]frontend.Variable, nGroups)
+
+	minHits := make([]frontend.Variable, nGroups)
+
+	maxHits := make([]frontend.Variable, nGroups)
 
-	for g := 0; g < lib.MAX_GROUPS; g++ {
-		groupSumAccum[g] = frontend.Variable(0)
+	for g := 0; g < nGroups; g++ {
+		groupCounts[g] = api.Mul(res[g], groupMask[g])
+
+		groupSums[g] = api.Mul(res[nGroups+g], groupMask[g])
+
+		groupMins[g] = frontend.Variable(0)
+
+		groupMaxs[g] = frontend.Variable(0)
+
+		minHits[g] = frontend.Variable(0)
+
+		maxHits[g] = frontend.Variable(0)
+
+		if !withExtrema {
+			continue
+		}
+
+		groupExtrema := api.Mul(groupMask[g], extrema)
+
+		groupMins[g] = api.Mul(res[2*nGroups+g], groupExtrema)
+
+		groupMaxs[g] = api.Mul(res[3*nGroups+g], groupExtrema)
+
+		minHits[g] = api.Mul(res[4*nGroups+g], groupExtrema)
+
+		maxHits[g] = api.Mul(res[5*nGroups+g], groupExtrema)
+
+		// Empty groups report 0, non-empty groups reach their extrema on some row
+		isEmpty := api.IsZero(groupCounts[g])
+
+		api.AssertIsEqual(api.Mul(groupMins[g], isEmpty), 0)
+
+		api.AssertIsEqual(api.Mul(groupMaxs[g], isEmpty), 0)
+
+		api.AssertIsEqual(api.Mul(api.IsZero(minHits[g]), api.Sub(1, isEmpty), groupExtrema), 0)
+
+		api.AssertIsEqual(api.Mul(api.IsZero(maxHits[g]), api.Sub(1, isEmpty), groupExtrema), 0)
 	}
 
-	// Track row match counts for validation
-	rowMatchCount := make([]frontend.Variable, lib.MAX_ROWS)
+	// Extrema: bound every row by its group's min/max, flag the rows reaching them
+	rowMinHits := make([]frontend.Variable, nRows)
+
+	rowMaxHits := make([]frontend.Variable, nRows)
 
-	// Process all rows
-	for row := 0; row < lib.MAX_ROWS; row++ {
-		maskedX := api.Mul(valuesX[row], rowMask[row])
+	if withExtrema {
+		keyTable := logderivlookup.New(api)
 
-		rowMatchAccum := frontend.Variable(0)
+		activeTable := logderivlookup.New(api)
 
-		for g := 0; g < lib.MAX_GROUPS; g++ {
-			isEq := lib.IsEqual(api, valuesY[row], groupKeys[g])
+		minTable := logderivlookup.New(api)
 
-			matchFlag := api.Mul(isEq, groupMask[g])
+		maxTable := logderivlookup.New(api)
 
-			contrib := api.Mul(maskedX, matchFlag)
+		for g := 0; g < nGroups; g++ {
+			keyTable.Insert(groupKeys[g])
 
-			groupSumAccum[g] = api.Add(groupSumAccum[g], contrib)
+			activeTable.Insert(groupMask[g])
 
-			rowMatchAccum = api.Add(rowMatchAccum, matchFlag)
+			minTable.Insert(groupMins[g])
+
+			maxTable.Insert(groupMaxs[g])
 		}
 
-		rowMatchCount[row] = rowMatchAccum
+		rowGroup := res[6*nGroups:]
+
+		rowKeys := keyTable.Lookup(rowGroup...)
+
+		rowActiveGroups := activeTable.Lookup(rowGroup...)
+
+		rowMins := minTable.Lookup(rowGroup...)
+
+		rowMaxs := maxTable.Lookup(rowGroup...)
+
+		for row := 0; row < nRows; row++ {
+			rowActive := api.Mul(rowMask[row], extrema)
+
+			// The hinted index must point to the row's (active) group
+			api.AssertIsEqual(api.Mul(api.Sub(rowKeys[row], valuesY[row]), rowActive), 0)
+
+			api.AssertIsEqual(api.Mul(api.Sub(rowActiveGroups[row], 1), rowActive), 0)
+
+			// Inactive rows compare zeros so the comparisons stay in range
+			cmpX := api.Mul(valuesX[row], rowActive)
+
+			curMin := api.Mul(rowMins[row], rowActive)
+
+			curMax := api.Mul(rowMaxs[row], rowActive)
+
+			api.AssertIsEqual(lib.ValueLessThan(api, cmpX, curMin), 0)
+
+			api.AssertIsEqual(lib.ValueLessThan(api, curMax, cmpX), 0)
+
+			rowMinHits[row] = api.Mul(lib.IsEqual(api, cmpX, curMin), rowActive)
+
+			rowMaxHits[row] = api.Mul(lib.IsEqual(api, cmpX, curMax), rowActive)
+		}
 	}
 
-	// VALIDATION: Each valid row MUST match exactly one group
-	for row := 0; row < lib.MAX_ROWS; row++ {
-		validationTerm := api.Mul(api.Sub(rowMatchCount[row], rowMask[row]), numGroups)
+	// Every value the identities depend on is committed before r is drawn
+	committed := make([]frontend.Variable, 0, 4*nRows+7*nGroups)
+
+	committed = append(committed, valuesX...)
+	committed = append(committed, valuesY...)
+	committed = append(committed, rowMask...)
+	committed = append(committed, groupKeys...)
+	committed = append(committed, groupCounts...)
+	committed = append(committed, groupSums...)
 
-		api.AssertIsEqual(validationTerm, 0)
+	if withExtrema {
+		committed = append(committed, groupMins...)
+		committed = append(committed, groupMaxs...)
+		committed = append(committed, minHits...)
+		committed = append(committed, maxHits...)
+		committed = append(committed, rowMinHits...)
+		committed = append(committed, rowMaxHits...)
 	}
 
-	// Build result (only GroupSums, no SSZ encoding)
-	var result SumColumnByGroupResult
+	// VALIDATION: rows and groups agree on counts, sums and extrema hits
+	// Scheduled at the end of Define, as lookup tables are: the commitment
+	// is taken once every committed value has been registered
+	api.Compiler().Defer(func(api frontend.API) error {
+		multicommit.WithCommitment(api, groupByIdentity(nRows, nGroups, withExtrema, enabled,
+			valuesX, valuesY, rowMask, rowMinHits, rowMaxHits,
+			groupKeys, groupCounts, groupSums, minHits, maxHits), committed...)
 
-	for g := 0; g < lib.MAX_GROUPS; g++ {
-		result.GroupSums[g] = groupSumAccum[g]
+		return nil
+	})
+
+	return SumColumnByGroupResult{
+		GroupSums:   groupSums,
+		GroupMins:   groupMins,
+		GroupMaxs:   groupMaxs,
+		GroupCounts: groupCounts,
 	}
+}
+
+// groupByIdentity returns the log-derivative checks of SumColumnByGroup at the point r
+func groupByIdentity(
+	nRows, nGroups int,
+	withExtrema bool,
+	enabled frontend.Variable,
+	valuesX, valuesY, rowMask, rowMinHits, rowMaxHits []frontend.Variable,
+	groupKeys, groupCounts, groupSums, minHits, maxHits []frontend.Variable,
+) multicommit.WithCommitmentFn {
+	return func(api frontend.API, r frontend.Variable) error {
+		rowCounts, rowSums := frontend.Variable(0), frontend.Variable(0)
+
+		rowMinTotal, rowMaxTotal := frontend.Variable(0), frontend.Variable(0)
+
+		for row := 0; row < nRows; row++ {
+			inv := api.Inverse(api.Sub(r, valuesY[row]))
 
-	return result
+			masked := api.Mul(rowMask[row], inv)
+
+			rowCounts = api.Add(rowCounts, masked)
+
+			rowSums = api.Add(rowSums, api.Mul(valuesX[row], masked))
+
+			if withExtrema {
+				rowMinTotal = api.Add(rowMinTotal, api.Mul(rowMinHits[row], inv))
+
+				rowMaxTotal = api.Add(rowMaxTotal, api.Mul(rowMaxHits[row], inv))
+			}
+		}
+
+		keyCounts, keySums := frontend.Variable(0), frontend.Variable(0)
+
+		keyMinTotal, keyMaxTotal := frontend.Variable(0), frontend.Variable(0)
+
+		for g := 0; g < nGroups; g++ {
+			inv := api.Inverse(api.Sub(r, groupKeys[g]))
+
+			keyCounts = api.Add(keyCounts, api.Mul(groupCounts[g], inv))
+
+			keySums = api.Add(keySums, api.Mul(groupSums[g], inv))
+
+			if withExtrema {
+				keyMinTotal = api.Add(keyMinTotal, api.Mul(minHits[g], inv))
+
+				keyMaxTotal = api.Add(keyMaxTotal, api.Mul(maxHits[g], inv))
+			}
+		}
+
+		api.AssertIsEqual(api.Mul(api.Sub(rowCounts, keyCounts), enabled), 0)
+
+		api.AssertIsEqual(api.Mul(api.Sub(rowSums, keySums), enabled), 0)
+
+		if withExtrema {
+			api.AssertIsEqual(api.Mul(api.Sub(rowMinTotal, keyMinTotal), enabled), 0)
+
+			api.AssertIsEqual(api.Mul(api.Sub(rowMaxTotal, keyMaxTotal), enabled), 0)
+		}
+
+		return nil
+	}
 }
@@ -0,0 +1,296 @@
+package operators
+
+import (
+	"math/big"
+	"testing"
+
+	"simple-verifier-gnark/internal/testutil"
+	"simple-verifier-gnark/pkg/lib"
+
+	"github.com/consensys/gnark/constraint/solver"
+	"github.com/consensys/gnark/frontend"
+)
+
+// groupByCircuit checks SumColumnByGroup over 6 rows and 4 group slots
+type groupByCircuit struct {
+	X         [6]frontend.Variable
+	Y         [6]frontend.Variable
+	Mask      [6]frontend.Variable
+	Keys      [4]frontend.Variable `gnark:",public"`
+	NumGroups frontend.Variable    `gnark:",public"`
+	Extrema   frontend.Variable    `gnark:",public"`
+	Sums      [4]frontend.Variable `gnark:",public"`
+	Mins      [4]frontend.Variable `gnark:",public"`
+	Maxs      [4]frontend.Variable `gnark:",public"`
+	Counts    [4]frontend.Variable `gnark:",public"`
+}
+
+func (c *groupByCircuit) Define(api frontend.API) error {
+	res := SumColumnByGroup(api, c.X[:], c.Y[:], c.Mask[:], c.Keys[:], c.NumGroups, c.Extrema)
+
+	for g := range c.Keys {
+		api.AssertIsEqual(res.GroupSums[g], c.Sums[g])
+
+		api.AssertIsEqual(res.GroupMins[g], c.Mins[g])
+
+		api.AssertIsEqual(res.GroupMaxs[g], c.Maxs[g])
+
+		api.AssertIsEqual(res.GroupCounts[g], c.Counts[g])
+	}
+
+	return nil
+}
+
+// groupBy returns the honest assignment over keys {1, 2, 3} (the last row is masked out):
+//
+//	x:   5  -3   8   2   7   4
+//	key: 1   2   1   3   2   1
+//
+// key 1: sum 13, count 2, min 5, max 8; key 2: sum 4, count 2, min -3, max 7;
+// key 3: sum 2, count 1, min = max = 2
+func groupBy() *groupByCircuit {
+	return &groupByCircuit{
+		X:         [6]frontend.Variable{5, fe(-3), 8, 2, 7, 4},
+		Y:         [6]frontend.Variable{1, 2, 1, 3, 2, 1},
+		Mask:      [6]frontend.Variable{1, 1, 1, 1, 1, 0},
+		Keys:      [4]frontend.Variable{1, 2, 3, 0},
+		NumGroups: 3,
+		Extrema:   1,
+		Sums:      [4]frontend.Variable{13, 4, 2, 0},
+		Mins:      [4]frontend.Variable{5, fe(-3), 2, 0},
+		Maxs:      [4]frontend.Variable{8, 7, 2, 0},
+		Counts:    [4]frontend.Variable{2, 2, 1, 0},
+	}
+}
+
+func TestSumColumnByGroupExtrema(t *testing.T) {
+	cases := []struct {
+		name   string
+		modify func(c *groupByCircuit)
+		want   bool
+	}{
+		{"honest", func(c *groupByCircuit) {}, true},
+		{"without extrema", func(c *groupByCircuit) {
+			c.Extrema = 0
+			c.Mins = [4]frontend.Variable{0, 0, 0, 0}
+			c.Maxs = [4]frontend.Variable{0, 0, 0, 0}
+		}, true},
+		{"filtered extremum", func(c *groupByCircuit) {
+			c.Mask[2] = 0
+			c.Sums[0], c.Counts[0], c.Maxs[0] = 5, 1, 5
+		}, true},
+		{"minimum below every row", func(c *groupByCircuit) { c.Mins[0] = 4 }, false},
+		{"minimum above a row", func(c *groupByCircuit) { c.Mins[1] = fe(-2) }, false},
+		{"maximum of a masked row", func(c *groupByCircuit) { c.Maxs[0] = 4 }, false},
+		{"maximum of another group", func(c *groupByCircuit) { c.Maxs[2] = 8 }, false},
+		{"extremum of an empty group", func(c *groupByCircuit) { c.Mins[3] = 1 }, false},
+		{"unsigned order of a negative", func(c *groupByCircuit) { c.Mins[1], c.Maxs[1] = 7, fe(-3) }, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assignment := groupBy()
+
+			tc.modify(assignment)
+
+			testutil.AssertSolved(t, &groupByCircuit{}, assignment, tc.want)
+		})
+	}
+}
+
+func TestSumColumnByGroupCounts(t *testing.T) {
+	cases := []struct {
+		name   string
+		modify func(c *groupByCircuit)
+		want   bool
+	}{
+		{"honest", func(c *groupByCircuit) {}, true},
+		{"empty selection", func(c *groupByCircuit) {
+			c.Mask = [6]frontend.Variable{0, 0, 0, 0, 0, 0}
+			c.Sums = [4]frontend.Variable{0, 0, 0, 0}
+			c.Mins = [4]frontend.Variable{0, 0, 0, 0}
+			c.Maxs = [4]frontend.Variable{0, 0, 0, 0}
+			c.Counts = [4]frontend.Variable{0, 0, 0, 0}
+		}, true},
+		{"group by disabled", func(c *groupByCircuit) {
+			*c = groupByCircuit{}
+			for i := range c.X {
+				c.X[i], c.Y[i], c.Mask[i] = 1, 9, 1
+			}
+			c.Keys = [4]frontend.Variable{0, 0, 0, 0}
+			c.NumGroups, c.Extrema = 0, 0
+			c.Sums, c.Mins, c.Maxs, c.Counts = c.Keys, c.Keys, c.Keys, c.Keys
+		}, true},
+		{"masked row counted", func(c *groupByCircuit) { c.Counts[0] = 3 }, false},
+		{"row moved to another group", func(c *groupByCircuit) { c.Counts[0], c.Counts[2] = 1, 2 }, false},
+		{"count in an inactive group", func(c *groupByCircuit) { c.Counts[3] = 1 }, false},
+		{"keys not increasing", func(c *groupByCircuit) {
+			c.Keys[0], c.Keys[1] = 2, 1
+			c.Sums[0], c.Sums[1] = 4, 13
+			c.Mins[0], c.Mins[1] = fe(-3), 5
+			c.Maxs[0], c.Maxs[1] = 7, 8
+		}, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assignment := groupBy()
+
+			tc.modify(assignment)
+
+			testutil.AssertSolved(t, &groupByCircuit{}, assignment, tc.want)
+		})
+	}
+}
+
+// Output blocks of lib.GroupByHint (4 group slots each, then one index per row)
+const (
+	hintCounts = iota
+	hintSums
+	hintMins
+	hintMaxs
+	hintMinHits
+	hintMaxHits
+	hintRows
+)
+
+// forgeGroupBy runs lib.GroupByHint, then lets tamper rewrite its output blocks
+// (values are reduced modulo the field afterwards)
+func forgeGroupBy(tamper func(out [][]*big.Int)) solver.Option {
+	return testutil.ForgeHint(lib.GroupByHint, func(field *big.Int, inputs, outputs []*big.Int) {
+		out := make([][]*big.Int, hintRows+1)
+
+		for block := 0; block < hintRows; block++ {
+			out[block] = outputs[4*block : 4*block+4]
+		}
+
+		out[hintRows] = outputs[4*hintRows:]
+
+		tamper(out)
+
+		for _, v := range outputs {
+			v.Mod(v, field)
+		}
+	})
+}
+
+// add adds delta to v in place
+func add(v *big.Int, delta int64) {
+	v.Add(v, big.NewInt(delta))
+}
+
+func TestSumColumnByGroupRejectsForgedHint(t *testing.T) {
+	cases := []struct {
+		name   string
+		tamper func(out [][]*big.Int)
+		modify func(c *groupByCircuit)
+		want   bool
+	}{
+		{
+			"honest",
+			func(out [][]*big.Int) {},
+			func(c *groupByCircuit) {},
+			true,
+		},
+		{
+			"wrong group sum",
+			func(out [][]*big.Int) { add(out[hintSums][0], 1) },
+			func(c *groupByCircuit) { c.Sums[0] = 14 },
+			false,
+		},
+		{
+			"sum moved between groups",
+			func(out [][]*big.Int) { add(out[hintSums][0], 1); add(out[hintSums][1], -1) },
+			func(c *groupByCircuit) { c.Sums[0], c.Sums[1] = 14, 3 },
+			false,
+		},
+		{
+			"wrong group count",
+			func(out [][]*big.Int) { add(out[hintCounts][2], 1) },
+			func(c *groupByCircuit) { c.Counts[2] = 2 },
+			false,
+		},
+		{
+			"minimum no row reaches",
+			func(out [][]*big.Int) { add(out[hintMins][0], -1) },
+			func(c *groupByCircuit) { c.Mins[0] = 4 },
+			false,
+		},
+		{
+			"maximum no row reaches",
+			func(out [][]*big.Int) { add(out[hintMaxs][0], 1) },
+			func(c *groupByCircuit) { c.Maxs[0] = 9 },
+			false,
+		},
+		{
+			"maximum without hits",
+			func(out [][]*big.Int) { add(out[hintMaxs][1], 1); out[hintMaxHits][1].SetInt64(0) },
+			func(c *groupByCircuit) { c.Maxs[1] = 8 },
+			false,
+		},
+		{
+			"maximum below a row",
+			func(out [][]*big.Int) { out[hintMaxs][0].SetInt64(5) },
+			func(c *groupByCircuit) { c.Maxs[0] = 5 },
+			false,
+		},
+		{
+			"wrong hit count",
+			func(out [][]*big.Int) { add(out[hintMinHits][0], 1) },
+			func(c *groupByCircuit) {},
+			false,
+		},
+		{
+			"wrong maximum hit count",
+			func(out [][]*big.Int) { add(out[hintMaxHits][2], 1) },
+			func(c *groupByCircuit) {},
+			false,
+		},
+		{
+			"row pointed to another group",
+			func(out [][]*big.Int) { out[hintRows][4].SetInt64(0) },
+			func(c *groupByCircuit) {},
+			false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assignment := groupBy()
+
+			tc.modify(assignment)
+
+			testutil.AssertSolved(t, &groupByCircuit{}, assignment, tc.want, forgeGroupBy(tc.tamper))
+		})
+	}
+}
+
+func TestSumColumnByGroupRejectsUnknownKey(t *testing.T) {
+	// Key 9 is not a group: the honest hint leaves row 3 out of every group
+	for _, extrema := range []int{1, 0} {
+		assignment := groupBy()
+
+		assignment.Y[3] = 9
+
+		assignment.Extrema = extrema
+
+		assignment.Sums[2], assignment.Counts[2] = 0, 0
+
+		assignment.Mins = [4]frontend.Variable{5, fe(-3), 0, 0}
+
+		assignment.Maxs = [4]frontend.Variable{8, 7, 0, 0}
+
+		if extrema == 0 {
+			assignment.Mins = [4]frontend.Variable{0, 0, 0, 0}
+
+			assignment.Maxs = [4]frontend.Variable{0, 0, 0, 0}
+		}
+
+		testutil.AssertSolved(t, &groupByCircuit{}, assignment, false)
+
+		// Masked out, the row's key is not checked
+		assignment.Mask[3] = 0
+
+		testutil.AssertSolved(t, &groupByCircuit{}, assignment, true)
+	}
+}
@@ -0,0 +1,40 @@
+package operators
+
+import (
+	"simple-verifier-gnark/pkg/lib"
+
+	"github.com/consensys/gnark/frontend"
+)
+
+// SumProductResult holds the outputs of SumProduct
+type SumProductResult struct {
+	Quotient  frontend.Variable
+	Remainder frontend.Variable
+}
+
+// SumProduct computes the dot product of two columns over masked rows
+// dot = sum(valuesX[i] * valuesY[i] * mask[i])
+//
+// Fixed-point: the product of two scaled columns carries scaleX*scaleY, so the
+// dot product is divided back by scaleY with a proven remainder:
+// dot == Quotient*scaleY + Remainder, Remainder < scaleY (Quotient is in scaleX).
+// Integer columns use scaleY = 1 (Remainder = 0).
+//
+// valuesX / valuesY are columns already selected from items (see lib.ColumnSelector)
+func SumProduct(api frontend.API, valuesX []frontend.Variable, valuesY []frontend.Variable, rowMask []frontend.Variable, scaleY frontend.Variable) SumProductResult {
+	products := make([]frontend.Variable, len(rowMask))
+
+	for row := 0; row < len(rowMask); row++ {
+		products[row] = api.Mul(valuesX[row], valuesY[row])
+	}
+
+	dot := lib.MaskedSum(api, products, rowMask)
+
+	// |dot| < 2^(2*VALUE_BITS + COUNT_BITS) since every valid cell is range checked
+	q, r := lib.DivMod(api, dot, scaleY, 2*lib.VALUE_BITS+lib.COUNT_BITS, lib.VALUE_BITS)
+
+	return SumProductResult{
+		Quotient:  q,
+		Remainder: r,
+	}
+}
@@ -0,0 +1,119 @@
+package operators
+
+import (
+	"testing"
+
+	"simple-verifier-gnark/internal/testutil"
+
+	"github.com/consensys/gnark/frontend"
+)
+
+// sumProductCircuit checks SumProduct over 4 rows against public expected results
+type sumProductCircuit struct {
+	X         [4]frontend.Variable
+	Y         [4]frontend.Variable
+	Mask      [4]frontend.Variable
+	ScaleY    frontend.Variable `gnark:",public"`
+	Quotient  frontend.Variable `gnark:",public"`
+	Remainder frontend.Variable `gnark:",public"`
+}
+
+func (c *sumProductCircuit) Define(api frontend.API) error {
+	res := SumProduct(api, c.X[:], c.Y[:], c.Mask[:], c.ScaleY)
+
+	api.AssertIsEqual(res.Quotient, c.Quotient)
+
+	api.AssertIsEqual(res.Remainder, c.Remainder)
+
+	return nil
+}
+
+// integerSumProduct is 2*4 + 3*5 - 1*6 = 17 (the last row is masked out)
+func integerSumProduct(q, r any) *sumProductCircuit {
+	return &sumProductCircuit{
+		X:         [4]frontend.Variable{2, 3, fe(-1), 5},
+		Y:         [4]frontend.Variable{4, 5, 6, 7},
+		Mask:      [4]frontend.Variable{1, 1, 1, 0},
+		ScaleY:    1,
+		Quotient:  q,
+		Remainder: r,
+	}
+}
+
+func TestSumProduct(t *testing.T) {
+	cases := []struct {
+		name       string
+		assignment *sumProductCircuit
+		want       bool
+	}{
+		{"dot product", integerSumProduct(17, 0), true},
+		{"masked row counted", integerSumProduct(52, 0), false},
+		{"wrong total", integerSumProduct(18, 0), false},
+		{"remainder of an integer column", integerSumProduct(16, 1), false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			testutil.AssertSolved(t, &sumProductCircuit{}, tc.assignment, tc.want)
+		})
+	}
+}
+
+func TestSumProductRejectsForgedQuotient(t *testing.T) {
+	// With scale 1 every q + k, r - k satisfies dot == q*1 + r: only r >= 0 pins q
+	for _, k := range []int64{1, 1000, 1 << 40, -1} {
+		assignment := integerSumProduct(fe(17+k), fe(-k))
+
+		testutil.AssertSolved(t, &sumProductCircuit{}, assignment, false, forgeDivMod(k))
+	}
+}
+
+// fixedPointSumProduct is 1.50*2.50 + 2.25*1.10 = 6.2250 at scale 100 x 100,
+// rescaled to 6.22 (X scale) with remainder 50
+func fixedPointSumProduct(q, r any) *sumProductCircuit {
+	return &sumProductCircuit{
+		X:         [4]frontend.Variable{150, 225, 0, 0},
+		Y:         [4]frontend.Variable{250, 110, 0, 0},
+		Mask:      [4]frontend.Variable{1, 1, 0, 0},
+		ScaleY:    100,
+		Quotient:  q,
+		Remainder: r,
+	}
+}
+
+func TestSumProductFixedPoint(t *testing.T) {
+	negative := &sumProductCircuit{
+		X:         [4]frontend.Variable{fe(-151), 0, 0, 0},
+		Y:         [4]frontend.Variable{250, 0, 0, 0},
+		Mask:      [4]frontend.Variable{1, 0, 0, 0},
+		ScaleY:    100,
+		Quotient:  fe(-378),
+		Remainder: 50,
+	}
+
+	cases := []struct {
+		name       string
+		assignment *sumProductCircuit
+		want       bool
+	}{
+		{"rescaled", fixedPointSumProduct(622, 50), true},
+		{"negative rounds down", negative, true},
+		{"not rescaled", fixedPointSumProduct(62250, 0), false},
+		{"remainder equal to scale", fixedPointSumProduct(621, 150), false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			testutil.AssertSolved(t, &sumProductCircuit{}, tc.assignment, tc.want)
+		})
+	}
+}
+
+func TestSumProductFixedPointRejectsNegativeRemainder(t *testing.T) {
+	// 62250 = 623*100 - 50: a rounded-up total needs a negative remainder
+	for _, k := range []int64{1, 7, 1 << 30} {
+		assignment := fixedPointSumProduct(fe(622+k), fe(50-100*k))
+
+		testutil.AssertSolved(t, &sumProductCircuit{}, assignment, false, forgeDivMod(k))
+	}
+}
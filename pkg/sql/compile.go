@@ -0,0 +1,379 @@
+// Package sql compiles a small SQL subset into the handlers and ops of a job
+//
+//	SELECT MERKLE16(*), COUNT(*), SUM(a), SUM(a) GROUP BY b,
+//	       SUM(price * qty) FILTER (WHERE b IN (3, 5)) AS revenue
+//	FROM t WHERE a >= 10 AND NOT delta < 0
+//
+// Each aggregate becomes one op (OpCodes / OpArgs, see lib.OpCodeNames) with the
+// WHERE clause, and its own FILTER clause, as row filter. GROUP BY (per aggregate,
+// or for the whole query) selects the *_BY opcode; its keys are the distinct
+// values of the group column in the data. Ops are packed in order into handlers
+// of at most maxOps ops; MERKLE16 sets the handler's column range, so MERKLE16 ops
+// over different ranges go to different handlers.
+package sql
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"simple-verifier-gnark/pkg/job"
+	"simple-verifier-gnark/pkg/lib"
+)
+
+// Compile compiles a query over the columns and rows of data into a validated job
+// The handlers of data are replaced; the table name is not checked.
+func Compile(text string, data *job.Job, maxOps int) (*job.Job, error) {
+	if maxOps < 1 {
+		return nil, fmt.Errorf("sql: maxOps must be >= 1, got %d", maxOps)
+	}
+
+	q, err := parse(text)
+
+	if err != nil {
+		return nil, err
+	}
+
+	c := &compiler{data: data, maxOps: maxOps, columns: make(map[string]int, len(data.Columns))}
+
+	for col, column := range data.Columns {
+		if column.Name != "" {
+			c.columns[column.Name] = col
+		}
+	}
+
+	where, err := c.filter(q.where)
+
+	if err != nil {
+		return nil, err
+	}
+
+	out := &job.Job{Columns: data.Columns, Rows: data.Rows}
+
+	for _, it := range q.items {
+		if it.fn == "" {
+			// The group column may be selected; its values are the op's group keys
+			if q.groupBy == "" || it.args[0] != q.groupBy {
+				return nil, fmt.Errorf("sql: at %d: column %s must be aggregated or be the GROUP BY column", it.pos, it.args[0])
+			}
+
+			continue
+		}
+
+		if it.groupBy == "" && it.fn != "MERKLE16" {
+			it.groupBy = q.groupBy
+		}
+
+		o, span, err := c.op(it, where)
+
+		if err != nil {
+			return nil, err
+		}
+
+		c.place(out, o, span)
+	}
+
+	if len(out.Handlers) == 0 {
+		return nil, fmt.Errorf("sql: no aggregates selected")
+	}
+
+	if err := out.Validate(); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// compiler resolves the column names of a query against a dataset
+type compiler struct {
+	data    *job.Job
+	maxOps  int
+	columns map[string]int
+}
+
+// column returns the index of a named column
+func (c *compiler) column(name string) (int, error) {
+	col, ok := c.columns[name]
+
+	if !ok {
+		return 0, fmt.Errorf("sql: unknown column %s", name)
+	}
+
+	return col, nil
+}
+
+// op compiles one aggregate; span is the [start, end) column range of a MERKLE16 op
+func (c *compiler) op(it item, where *job.Filter) (job.Op, [2]int, error) {
+	o := job.Op{Label: it.label}
+
+	fail := func(format string, args ...any) (job.Op, [2]int, error) {
+		return job.Op{}, [2]int{}, fmt.Errorf("sql: at %d: %s: %s", it.pos, it.label, fmt.Sprintf(format, args...))
+	}
+
+	cols := make([]int, len(it.args))
+
+	for i, name := range it.args {
+		col, err := c.column(name)
+
+		if err != nil {
+			return job.Op{}, [2]int{}, err
+		}
+
+		cols[i] = col
+	}
+
+	if it.product && (it.fn != "SUM" || len(cols) != 2) {
+		return fail("only SUM takes a product of two columns")
+	}
+
+	if !it.product && len(cols) > 1 && it.fn != "MERKLE16" {
+		return fail("%s takes one column", it.fn)
+	}
+
+	if it.star && it.fn != "COUNT" && it.fn != "MERKLE16" {
+		return fail("%s(*) is not supported", it.fn)
+	}
+
+	filter, err := c.filter(it.filter)
+
+	if err != nil {
+		return job.Op{}, [2]int{}, err
+	}
+
+	o.Where = and(where, filter)
+
+	if it.fn == "MERKLE16" {
+		if it.groupBy != "" {
+			return fail("GROUP BY is not supported for MERKLE16")
+		}
+
+		span := [2]int{0, len(c.data.Columns)}
+
+		if !it.star {
+			span = [2]int{cols[0], cols[0] + len(cols)}
+
+			for i, col := range cols {
+				if col != cols[0]+i {
+					return fail("columns must be adjacent and in table order")
+				}
+			}
+		}
+
+		o.Op = "MERKLE16"
+
+		return o, span, nil
+	}
+
+	if len(cols) > 0 {
+		o.X = cols[0]
+	}
+
+	switch {
+	case it.product && it.groupBy != "":
+		return fail("GROUP BY is not supported for SUM(col * col)")
+	case it.product:
+		o.Op, o.Y = "SUM_PRODUCT", cols[1]
+	case it.fn == "AVG" && it.groupBy != "":
+		return fail("GROUP BY is not supported for AVG")
+	case it.fn == "AVG":
+		o.Op = "AVG_COL"
+	case it.fn == "COUNT":
+		o.Op, o.X = "COUNT", 0
+	default:
+		o.Op = it.fn + "_COL"
+	}
+
+	if it.groupBy == "" {
+		return o, [2]int{}, nil
+	}
+
+	groupCol, err := c.column(it.groupBy)
+
+	if err != nil {
+		return job.Op{}, [2]int{}, err
+	}
+
+	o.Op, o.Y = o.Op+"_BY", groupCol
+
+	if it.fn == "COUNT" {
+		o.Op = "COUNT_BY"
+	}
+
+	o.Groups, err = c.keys(groupCol)
+
+	if err != nil {
+		return job.Op{}, [2]int{}, err
+	}
+
+	return o, [2]int{}, nil
+}
+
+// keys returns the distinct values of a column in increasing order
+func (c *compiler) keys(col int) ([]json.Number, error) {
+	seen := make(map[string]bool)
+
+	var values []*big.Int
+
+	for row, cells := range c.data.Rows {
+		if col >= len(cells) {
+			return nil, fmt.Errorf("sql: row %d has no column %d", row, col)
+		}
+
+		v, ok := new(big.Int).SetString(cells[col].String(), 10)
+
+		if !ok {
+			return nil, fmt.Errorf("sql: row %d: invalid integer %q", row, cells[col])
+		}
+
+		if !seen[v.String()] {
+			seen[v.String()] = true
+
+			values = append(values, v)
+		}
+	}
+
+	sort.Slice(values, func(i, k int) bool { return values[i].Cmp(values[k]) < 0 })
+
+	keys := make([]json.Number, len(values))
+
+	for i, v := range values {
+		keys[i] = json.Number(v.String())
+	}
+
+	return keys, nil
+}
+
+// filter converts an expression to a job filter (nil stays nil)
+// AND / OR chains become balanced trees so n predicates need n leaves, not 2^(n-1)
+func (c *compiler) filter(e *expr) (*job.Filter, error) {
+	if e == nil {
+		return nil, nil
+	}
+
+	switch e.kind {
+	case "not":
+		f, err := c.filter(e.args[0])
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &job.Filter{Not: f}, nil
+	case "and", "or":
+		filters := make([]*job.Filter, len(e.args))
+
+		for i, arg := range e.args {
+			f, err := c.filter(arg)
+
+			if err != nil {
+				return nil, err
+			}
+
+			filters[i] = f
+		}
+
+		return balance(e.kind, filters), nil
+	}
+
+	col, err := c.column(e.col)
+
+	if err != nil {
+		return nil, err
+	}
+
+	scale := c.data.Columns[col].Scale
+
+	a, err := job.ParseDecimal(e.a, scale)
+
+	if err != nil {
+		return nil, fmt.Errorf("sql: column %s: %w", e.col, err)
+	}
+
+	f := &job.Filter{Col: col, Pred: e.pred, A: json.Number(a.String())}
+
+	if e.pred != "BETWEEN" {
+		return f, nil
+	}
+
+	// BETWEEN lo AND hi is RANGE [lo, hi + 1). Values are below 2^VALUE_BITS, so from
+	// hi = 2^VALUE_BITS - 1 up (where hi + 1 leaves the comparison domain) the upper
+	// bound holds for every row and it is GE lo
+	hi, err := job.ParseDecimal(e.b, scale)
+
+	if err != nil {
+		return nil, fmt.Errorf("sql: column %s: %w", e.col, err)
+	}
+
+	end := new(big.Int).Add(hi, big.NewInt(1))
+
+	if end.Cmp(new(big.Int).Lsh(big.NewInt(1), lib.VALUE_BITS)) >= 0 {
+		f.Pred = "GE"
+
+		return f, nil
+	}
+
+	f.Pred, f.B = "RANGE", json.Number(end.String())
+
+	return f, nil
+}
+
+// place appends op o to the last handler, or to a new one when it is full or
+// o is a MERKLE16 op over another column range than the handler's MERKLE16 ops
+func (c *compiler) place(out *job.Job, o job.Op, span [2]int) {
+	n := len(out.Handlers)
+
+	full := n == 0 || len(out.Handlers[n-1].Ops) >= c.maxOps
+
+	if !full && o.Op == "MERKLE16" {
+		h := out.Handlers[n-1]
+
+		for _, other := range h.Ops {
+			full = full || (other.Op == "MERKLE16" && (h.Start != span[0] || h.NC != span[1]-span[0]))
+		}
+	}
+
+	if full {
+		out.Handlers = append(out.Handlers, job.Handler{})
+
+		n++
+	}
+
+	h := &out.Handlers[n-1]
+
+	if o.Op == "MERKLE16" {
+		h.Start, h.NC = span[0], span[1]-span[0]
+	}
+
+	h.Ops = append(h.Ops, o)
+}
+
+// balance joins filters with AND / OR as a balanced binary tree
+func balance(kind string, filters []*job.Filter) *job.Filter {
+	if len(filters) == 1 {
+		return filters[0]
+	}
+
+	mid := len(filters) / 2
+
+	left, right := balance(kind, filters[:mid]), balance(kind, filters[mid:])
+
+	if kind == "or" {
+		return &job.Filter{Or: []*job.Filter{left, right}}
+	}
+
+	return &job.Filter{And: []*job.Filter{left, right}}
+}
+
+// and joins two optional filters
+func and(a, b *job.Filter) *job.Filter {
+	if a == nil {
+		return b
+	}
+
+	if b == nil {
+		return a
+	}
+
+	return &job.Filter{And: []*job.Filter{a, b}}
+}
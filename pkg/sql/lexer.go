@@ -0,0 +1,123 @@
+package sql
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Token kinds
+const (
+	TOKEN_EOF    = 0
+	TOKEN_IDENT  = 1 // column, table, function or keyword (case-insensitive)
+	TOKEN_NUMBER = 2 // decimal integer or fraction (digits.digits), without sign
+	TOKEN_SYMBOL = 3 // ( ) , * ; = != <> < <= > >=
+)
+
+// token is one lexeme of a query and its position (rune index, as in errors)
+type token struct {
+	kind int
+	text string
+	pos  int
+}
+
+// is reports whether t is the keyword or symbol s (keywords ignore case)
+func (t token) is(s string) bool {
+	if t.kind == TOKEN_IDENT {
+		return strings.EqualFold(t.text, s)
+	}
+
+	return t.kind == TOKEN_SYMBOL && t.text == s
+}
+
+// String returns the token as quoted in errors
+func (t token) String() string {
+	if t.kind == TOKEN_EOF {
+		return "end of query"
+	}
+
+	return fmt.Sprintf("%q", t.text)
+}
+
+// lex splits a query into tokens, ending with TOKEN_EOF
+func lex(query string) ([]token, error) {
+	var tokens []token
+
+	runes := []rune(query)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			// Comment to end of line
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+
+			tokens = append(tokens, token{kind: TOKEN_IDENT, text: string(runes[start:i]), pos: start})
+		case unicode.IsDigit(r):
+			start := i
+
+			for i < len(runes) && unicode.IsDigit(runes[i]) {
+				i++
+			}
+
+			if i+1 < len(runes) && runes[i] == '.' && unicode.IsDigit(runes[i+1]) {
+				i++
+
+				for i < len(runes) && unicode.IsDigit(runes[i]) {
+					i++
+				}
+			}
+
+			tokens = append(tokens, token{kind: TOKEN_NUMBER, text: string(runes[start:i]), pos: start})
+		case r == '"':
+			// Quoted identifier
+			start := i
+
+			i++
+
+			for i < len(runes) && runes[i] != '"' {
+				i++
+			}
+
+			if i == len(runes) {
+				return nil, fmt.Errorf("sql: at %d: unterminated quoted identifier", start)
+			}
+
+			tokens = append(tokens, token{kind: TOKEN_IDENT, text: string(runes[start+1 : i]), pos: start})
+
+			i++
+		default:
+			start := i
+
+			two := ""
+
+			if i+1 < len(runes) {
+				two = string(runes[i : i+2])
+			}
+
+			switch {
+			case two == "!=" || two == "<>" || two == "<=" || two == ">=":
+				i += 2
+			case strings.ContainsRune("(),*;=<>-", r):
+				i++
+			default:
+				return nil, fmt.Errorf("sql: at %d: unexpected character %q", start, r)
+			}
+
+			tokens = append(tokens, token{kind: TOKEN_SYMBOL, text: string(runes[start:i]), pos: start})
+		}
+	}
+
+	return append(tokens, token{kind: TOKEN_EOF, pos: len(runes)}), nil
+}
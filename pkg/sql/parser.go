@@ -0,0 +1,547 @@
+package sql
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// query is a parsed SELECT statement (column names are resolved by Compile)
+type query struct {
+	items   []item
+	table   string
+	where   *expr
+	groupBy string
+}
+
+// item is one select item: an aggregate, or a plain column (fn == "")
+type item struct {
+	fn      string
+	star    bool
+	args    []string
+	product bool
+	filter  *expr
+	groupBy string
+	label   string
+	pos     int
+}
+
+// expr is a WHERE expression node
+// kind is "and" / "or" (args, flattened), "not" (args[0]) or "leaf" (col pred a [b])
+type expr struct {
+	kind string
+	args []*expr
+
+	col  string
+	pred string
+	a    json.Number
+	b    json.Number
+}
+
+// aggregates lists the supported aggregate functions
+var aggregates = map[string]bool{
+	"COUNT":    true,
+	"SUM":      true,
+	"MIN":      true,
+	"MAX":      true,
+	"AVG":      true,
+	"MERKLE16": true,
+}
+
+// keywords cannot be used as column or table names (quote them: "from")
+var keywords = map[string]bool{
+	"SELECT":  true,
+	"FROM":    true,
+	"WHERE":   true,
+	"GROUP":   true,
+	"BY":      true,
+	"FILTER":  true,
+	"AS":      true,
+	"AND":     true,
+	"OR":      true,
+	"NOT":     true,
+	"BETWEEN": true,
+	"IN":      true,
+}
+
+// comparisons maps comparison operators to predicate names (lib.PredicateNames)
+var comparisons = map[string]string{
+	"=":  "EQ",
+	"!=": "NE",
+	"<>": "NE",
+	"<":  "LT",
+	"<=": "LE",
+	">":  "GT",
+	">=": "GE",
+}
+
+// flipped maps a predicate to the one with swapped operands (5 < a is a > 5)
+var flipped = map[string]string{
+	"EQ": "EQ",
+	"NE": "NE",
+	"LT": "GT",
+	"LE": "GE",
+	"GT": "LT",
+	"GE": "LE",
+}
+
+// parser is a recursive descent parser over the tokens of a query
+type parser struct {
+	text   []rune
+	tokens []token
+	pos    int
+}
+
+// parse parses a SELECT statement
+//
+//	SELECT item, ... FROM table [WHERE expr] [GROUP BY column] [;]
+//	item: COUNT(*) | COUNT(col) | SUM(col) | SUM(col * col) | MIN(col) | MAX(col) | AVG(col)
+//	      | MERKLE16(*) | MERKLE16(col, ...) | col
+//	      followed by [FILTER (WHERE expr)] [GROUP BY column] [AS name]
+//	expr: expr OR expr | expr AND expr | NOT expr | (expr)
+//	      | col op n | n op col | col [NOT] BETWEEN n AND n | col [NOT] IN (n, ...)
+func parse(text string) (*query, error) {
+	tokens, err := lex(text)
+
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{text: []rune(text), tokens: tokens}
+
+	return p.query()
+}
+
+// peek returns the current token
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+// next consumes the current token
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+
+	if t.kind != TOKEN_EOF {
+		p.pos++
+	}
+
+	return t
+}
+
+// accept consumes the current token if it is the keyword or symbol s
+func (p *parser) accept(s string) bool {
+	if p.peek().is(s) {
+		p.next()
+
+		return true
+	}
+
+	return false
+}
+
+// expect consumes the keyword or symbol s
+func (p *parser) expect(s string) error {
+	if !p.accept(s) {
+		return p.errorf(p.peek(), "expected %s, got %s", s, p.peek())
+	}
+
+	return nil
+}
+
+// ident consumes an identifier
+func (p *parser) ident(what string) (string, error) {
+	t := p.next()
+
+	if t.kind != TOKEN_IDENT || (keywords[strings.ToUpper(t.text)] && p.text[t.pos] != '"') {
+		return "", p.errorf(t, "expected %s, got %s", what, t)
+	}
+
+	return t.text, nil
+}
+
+// number consumes a signed decimal number, scaled to its column by the compiler
+func (p *parser) number() (json.Number, error) {
+	sign := ""
+
+	if p.accept("-") {
+		sign = "-"
+	}
+
+	t := p.next()
+
+	if t.kind != TOKEN_NUMBER {
+		return "", p.errorf(t, "expected a number, got %s", t)
+	}
+
+	return json.Number(sign + t.text), nil
+}
+
+// errorf returns a parse error at token t
+func (p *parser) errorf(t token, format string, args ...any) error {
+	return fmt.Errorf("sql: at %d: %s", t.pos, fmt.Sprintf(format, args...))
+}
+
+// query parses the whole statement
+func (p *parser) query() (*query, error) {
+	if err := p.expect("SELECT"); err != nil {
+		return nil, err
+	}
+
+	q := &query{}
+
+	for {
+		it, err := p.item()
+
+		if err != nil {
+			return nil, err
+		}
+
+		q.items = append(q.items, it)
+
+		if !p.accept(",") {
+			break
+		}
+	}
+
+	if err := p.expect("FROM"); err != nil {
+		return nil, err
+	}
+
+	table, err := p.ident("a table name")
+
+	if err != nil {
+		return nil, err
+	}
+
+	q.table = table
+
+	if p.accept("WHERE") {
+		if q.where, err = p.or(); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.accept("GROUP") {
+		if q.groupBy, err = p.groupBy(); err != nil {
+			return nil, err
+		}
+	}
+
+	p.accept(";")
+
+	if t := p.peek(); t.kind != TOKEN_EOF {
+		return nil, p.errorf(t, "unexpected %s", t)
+	}
+
+	return q, nil
+}
+
+// groupBy parses "BY column" after GROUP
+func (p *parser) groupBy() (string, error) {
+	if err := p.expect("BY"); err != nil {
+		return "", err
+	}
+
+	return p.ident("a column")
+}
+
+// item parses one select item
+func (p *parser) item() (item, error) {
+	start := p.peek()
+
+	name, err := p.ident("an aggregate or a column")
+
+	if err != nil {
+		return item{}, err
+	}
+
+	it := item{pos: start.pos}
+
+	if !p.accept("(") {
+		it.args = []string{name}
+	} else {
+		it.fn = strings.ToUpper(name)
+
+		if !aggregates[it.fn] {
+			return item{}, p.errorf(start, "unknown aggregate %s", name)
+		}
+
+		if err := p.aggregateArgs(&it); err != nil {
+			return item{}, err
+		}
+
+		if p.accept("FILTER") {
+			if err := p.expect("("); err != nil {
+				return item{}, err
+			}
+
+			if err := p.expect("WHERE"); err != nil {
+				return item{}, err
+			}
+
+			if it.filter, err = p.or(); err != nil {
+				return item{}, err
+			}
+
+			if err := p.expect(")"); err != nil {
+				return item{}, err
+			}
+		}
+
+		if p.accept("GROUP") {
+			if it.groupBy, err = p.groupBy(); err != nil {
+				return item{}, err
+			}
+		}
+	}
+
+	it.label = strings.TrimSpace(string(p.text[start.pos:p.peek().pos]))
+
+	if p.accept("AS") {
+		if it.label, err = p.ident("an alias"); err != nil {
+			return item{}, err
+		}
+	}
+
+	return it, nil
+}
+
+// aggregateArgs parses the arguments and closing parenthesis of an aggregate
+func (p *parser) aggregateArgs(it *item) error {
+	if p.accept("*") {
+		it.star = true
+
+		return p.expect(")")
+	}
+
+	for {
+		col, err := p.ident("a column")
+
+		if err != nil {
+			return err
+		}
+
+		it.args = append(it.args, col)
+
+		if p.accept("*") {
+			it.product = true
+
+			continue
+		}
+
+		if !p.accept(",") {
+			break
+		}
+	}
+
+	return p.expect(")")
+}
+
+// or parses a disjunction
+func (p *parser) or() (*expr, error) {
+	return p.chain("OR", "or", p.and)
+}
+
+// and parses a conjunction
+func (p *parser) and() (*expr, error) {
+	return p.chain("AND", "and", p.not)
+}
+
+// chain parses operands joined by keyword into one flattened node
+func (p *parser) chain(keyword, kind string, operand func() (*expr, error)) (*expr, error) {
+	first, err := operand()
+
+	if err != nil {
+		return nil, err
+	}
+
+	args := []*expr{first}
+
+	for p.accept(keyword) {
+		e, err := operand()
+
+		if err != nil {
+			return nil, err
+		}
+
+		args = append(args, e)
+	}
+
+	if len(args) == 1 {
+		return first, nil
+	}
+
+	// (a AND b) AND c is one node of 3 operands
+	var flat []*expr
+
+	for _, e := range args {
+		if e.kind == kind {
+			flat = append(flat, e.args...)
+		} else {
+			flat = append(flat, e)
+		}
+	}
+
+	return &expr{kind: kind, args: flat}, nil
+}
+
+// not parses an optionally negated primary expression
+func (p *parser) not() (*expr, error) {
+	if p.accept("NOT") {
+		e, err := p.not()
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &expr{kind: "not", args: []*expr{e}}, nil
+	}
+
+	return p.primary()
+}
+
+// primary parses a parenthesized expression or a comparison
+func (p *parser) primary() (*expr, error) {
+	if p.accept("(") {
+		e, err := p.or()
+
+		if err != nil {
+			return nil, err
+		}
+
+		return e, p.expect(")")
+	}
+
+	// n op col
+	if t := p.peek(); t.kind == TOKEN_NUMBER || t.is("-") {
+		value, err := p.number()
+
+		if err != nil {
+			return nil, err
+		}
+
+		pred, err := p.comparison()
+
+		if err != nil {
+			return nil, err
+		}
+
+		col, err := p.ident("a column")
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &expr{kind: "leaf", col: col, pred: flipped[pred], a: value}, nil
+	}
+
+	col, err := p.ident("a column")
+
+	if err != nil {
+		return nil, err
+	}
+
+	negate := p.accept("NOT")
+
+	var e *expr
+
+	switch {
+	case p.accept("BETWEEN"):
+		e, err = p.between(col)
+	case p.accept("IN"):
+		e, err = p.in(col)
+	case negate:
+		return nil, p.errorf(p.peek(), "expected BETWEEN or IN after NOT, got %s", p.peek())
+	default:
+		pred, err := p.comparison()
+
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := p.number()
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &expr{kind: "leaf", col: col, pred: pred, a: value}, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if negate {
+		return &expr{kind: "not", args: []*expr{e}}, nil
+	}
+
+	return e, nil
+}
+
+// comparison consumes a comparison operator and returns its predicate name
+func (p *parser) comparison() (string, error) {
+	t := p.next()
+
+	pred, ok := comparisons[t.text]
+
+	if t.kind != TOKEN_SYMBOL || !ok {
+		return "", p.errorf(t, "expected a comparison, got %s", t)
+	}
+
+	return pred, nil
+}
+
+// between parses "lo AND hi" (inclusive), compiled to RANGE [lo, hi + 1) once scaled
+func (p *parser) between(col string) (*expr, error) {
+	lo, err := p.number()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.expect("AND"); err != nil {
+		return nil, err
+	}
+
+	hi, err := p.number()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &expr{kind: "leaf", col: col, pred: "BETWEEN", a: lo, b: hi}, nil
+}
+
+// in parses "(n, ...)" as an OR of equalities
+func (p *parser) in(col string) (*expr, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+
+	e := &expr{kind: "or"}
+
+	for {
+		value, err := p.number()
+
+		if err != nil {
+			return nil, err
+		}
+
+		e.args = append(e.args, &expr{kind: "leaf", col: col, pred: "EQ", a: value})
+
+		if !p.accept(",") {
+			break
+		}
+	}
+
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+
+	if len(e.args) == 1 {
+		return e.args[0], nil
+	}
+
+	return e, nil
+}
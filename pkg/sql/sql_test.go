@@ -0,0 +1,241 @@
+package sql_test
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"simple-verifier-gnark/pkg/circuit"
+	"simple-verifier-gnark/pkg/job"
+	"simple-verifier-gnark/pkg/sql"
+
+	"github.com/consensys/gnark-crypto/ecc"
+)
+
+// table returns a dataset of columns id, a, key, b, price and qty
+func table(t *testing.T) *job.Job {
+	t.Helper()
+
+	schema := job.Schema{Columns: []job.Column{
+		{Name: "id", Bits: 8},
+		{Name: "a", Bits: 8},
+		{Name: "key", Bits: 8},
+		{Name: "b", Bits: 8, Signed: true},
+		{Name: "price", Bits: 16, Scale: 100},
+		{Name: "qty", Bits: 16},
+	}}
+
+	data := "id,a,key,b,price,qty\n1,3,1,-2,125,4\n2,5,2,7,250,1\n3,8,1,0,100,2\n"
+
+	j, err := job.ReadCSV(strings.NewReader(data), schema)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return j
+}
+
+func TestCompile(t *testing.T) {
+	query := `SELECT MERKLE16(id, a, key, b), COUNT(*), SUM(a) GROUP BY key,
+	                 SUM(b) FILTER (WHERE key IN (1, 3) AND NOT id < 2) AS late_b,
+	                 AVG(a), SUM(price * qty), MERKLE16(*)
+	          FROM t WHERE a >= 3`
+
+	j, err := sql.Compile(query, table(t), 4)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ops []string
+
+	for _, handler := range j.Handlers {
+		for _, o := range handler.Ops {
+			ops = append(ops, o.Op)
+		}
+	}
+
+	want := "MERKLE16 COUNT SUM_COL_BY SUM_COL AVG_COL SUM_PRODUCT MERKLE16"
+
+	if got := strings.Join(ops, " "); got != want {
+		t.Fatalf("ops = %s, want %s", got, want)
+	}
+
+	if len(j.Handlers) != 2 || j.Handlers[0].Start != 0 || j.Handlers[0].NC != 4 || j.Handlers[1].NC != 6 {
+		t.Fatalf("handlers = %+v", j.Handlers)
+	}
+
+	grouped := j.Handlers[0].Ops[2]
+
+	if grouped.X != 1 || grouped.Y != 2 || len(grouped.Groups) != 2 || grouped.Groups[0] != "1" || grouped.Groups[1] != "2" {
+		t.Errorf("SUM(a) GROUP BY key = %+v", grouped)
+	}
+
+	if label := j.Handlers[0].Ops[3].Label; label != "late_b" {
+		t.Errorf("label = %q, want late_b", label)
+	}
+
+	// WHERE applies to every aggregate, MERKLE16 included
+	for h, handler := range j.Handlers {
+		for op, o := range handler.Ops {
+			if o.Where == nil {
+				t.Errorf("handler %d op %d (%s) is not filtered", h, op, o.Op)
+			}
+		}
+	}
+}
+
+func TestBetween(t *testing.T) {
+	// u spans the whole unsigned domain: 0 and 2^64 - 1
+	data := "u,d\n0,-3\n18446744073709551615,100\n7,-128\n"
+
+	j, err := job.ReadCSV(strings.NewReader(data), job.Schema{Columns: []job.Column{{Name: "u"}, {Name: "d", Bits: 8, Signed: true}}})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		where string
+		pred  string
+		a, b  string
+		count int64
+	}{
+		{"u BETWEEN 1 AND 7", "RANGE", "1", "8", 1},
+		{"d BETWEEN -128 AND -3", "RANGE", "-128", "-2", 2},
+		{"d NOT BETWEEN -3 AND 99", "RANGE", "-3", "100", 2},
+
+		// hi + 1 = 2^64 leaves the comparison domain: only the lower bound is kept
+		{"u BETWEEN 7 AND 18446744073709551615", "GE", "7", "", 2},
+		{"u BETWEEN 0 AND 99999999999999999999999", "GE", "0", "", 3},
+	}
+
+	shape := circuit.Config{MaxRows: 4, MaxCols: 2, MaxGroups: 2, MaxOps: 1, MaxHandlers: 1, MaxPredicates: 1}
+
+	for _, tc := range cases {
+		t.Run(tc.where, func(t *testing.T) {
+			out, err := sql.Compile("SELECT COUNT(*) FROM t WHERE "+tc.where, j, 1)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			leaf := out.Handlers[0].Ops[0].Where
+
+			if leaf.Not != nil {
+				leaf = leaf.Not
+			}
+
+			if leaf.Pred != tc.pred || string(leaf.A) != tc.a || string(leaf.B) != tc.b {
+				t.Fatalf("filter %s [%s, %s), want %s [%s, %s)", leaf.Pred, leaf.A, leaf.B, tc.pred, tc.a, tc.b)
+			}
+
+			assignment, err := out.Assignment(shape, ecc.BN254)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := assignment.Results[0][0][0].(*big.Int); got.Int64() != tc.count {
+				t.Errorf("COUNT = %s, want %d", got, tc.count)
+			}
+		})
+	}
+}
+
+func TestDecimalLiterals(t *testing.T) {
+	// price has scale 100 (rows 1.25, 2.50, 1.00): decimals are scaled, integers are not
+	cases := []struct {
+		where string
+		pred  string
+		a, b  string
+		count int64
+	}{
+		{"price > 1.25", "GT", "125", "", 1},
+		{"1.25 <= price", "GE", "125", "", 2},
+		{"price > -0.5", "GT", "-50", "", 3},
+		{"price BETWEEN 1.0 AND 2.49", "RANGE", "100", "250", 2},
+		{"price = 250", "EQ", "250", "", 1},
+	}
+
+	shape := circuit.Config{MaxRows: 4, MaxCols: 6, MaxGroups: 2, MaxOps: 1, MaxHandlers: 1, MaxPredicates: 1}
+
+	for _, tc := range cases {
+		t.Run(tc.where, func(t *testing.T) {
+			out, err := sql.Compile("SELECT COUNT(*) FROM t WHERE "+tc.where, table(t), 1)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			leaf := out.Handlers[0].Ops[0].Where
+
+			if leaf.Pred != tc.pred || string(leaf.A) != tc.a || string(leaf.B) != tc.b {
+				t.Fatalf("filter %s [%s, %s), want %s [%s, %s)", leaf.Pred, leaf.A, leaf.B, tc.pred, tc.a, tc.b)
+			}
+
+			assignment, err := out.Assignment(shape, ecc.BN254)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := assignment.Results[0][0][0].(*big.Int); got.Int64() != tc.count {
+				t.Errorf("COUNT = %s, want %d", got, tc.count)
+			}
+		})
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	cases := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		// Lexer
+		{"unexpected character", "SELECT COUNT(*) FROM t WHERE a @ 1", `sql: at 31: unexpected character '@'`},
+		{"unterminated quote", `SELECT SUM("a) FROM t`, "sql: at 11: unterminated quoted identifier"},
+		{"position in runes", `SELECT COUNT(*) FROM t WHERE "é" @ 1`, `sql: at 33: unexpected character '@'`},
+
+		// Parser
+		{"unknown aggregate", "SELECT MEDIAN(a) FROM t", "sql: at 7: unknown aggregate MEDIAN"},
+		{"missing FROM", "SELECT COUNT(*)", "expected FROM"},
+		{"trailing comma", "SELECT COUNT(*), FROM t", "sql: at 17:"},
+		{"trailing tokens", "SELECT COUNT(*) FROM t LIMIT 3", `sql: at 23: unexpected "LIMIT"`},
+		{"NOT without BETWEEN or IN", "SELECT COUNT(*) FROM t WHERE a NOT = 3", "expected BETWEEN or IN after NOT"},
+		{"missing comparison", "SELECT COUNT(*) FROM t WHERE a 3", "expected a comparison"},
+		{"missing number", "SELECT COUNT(*) FROM t WHERE a < b", "expected a number"},
+
+		// Compiler
+		{"unknown column", "SELECT SUM(z) FROM t", "sql: unknown column z"},
+		{"unknown filter column", "SELECT COUNT(*) FROM t WHERE z = 1", "sql: unknown column z"},
+		{"too many decimals", "SELECT COUNT(*) FROM t WHERE price < 1.234", `sql: column price: "1.234" has more fractional digits than scale 100 allows`},
+		{"decimal of an integer column", "SELECT COUNT(*) FROM t WHERE b BETWEEN -0.5 AND 3", `sql: column b: "-0.5" has more fractional digits than scale 1 allows`},
+		{"product of a non-SUM", "SELECT MIN(a * b) FROM t", "only SUM takes a product of two columns"},
+		{"two columns", "SELECT SUM(a, b) FROM t", "SUM takes one column"},
+		{"star", "SELECT SUM(*) FROM t", "SUM(*) is not supported"},
+		{"non-adjacent MERKLE16", "SELECT MERKLE16(id, key) FROM t", "columns must be adjacent and in table order"},
+		{"grouped MERKLE16", "SELECT MERKLE16(*) GROUP BY key FROM t", "GROUP BY is not supported for MERKLE16"},
+		{"grouped product", "SELECT SUM(price * qty) GROUP BY key FROM t", "GROUP BY is not supported for SUM(col * col)"},
+		{"grouped AVG", "SELECT AVG(a) GROUP BY key FROM t", "GROUP BY is not supported for AVG"},
+		{"plain column", "SELECT a, COUNT(*) FROM t", "column a must be aggregated or be the GROUP BY column"},
+		{"plain column not grouped", "SELECT a, SUM(b) FROM t GROUP BY key", "column a must be aggregated or be the GROUP BY column"},
+		{"no aggregates", "SELECT key FROM t GROUP BY key", "sql: no aggregates selected"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := sql.Compile(tc.query, table(t), 4)
+
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("error %v, want %q", err, tc.want)
+			}
+		})
+	}
+
+	if _, err := sql.Compile("SELECT COUNT(*) FROM t", table(t), 0); err == nil || err.Error() != "sql: maxOps must be >= 1, got 0" {
+		t.Fatalf("maxOps 0: error %v", err)
+	}
+}
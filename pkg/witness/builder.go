@@ -0,0 +1,363 @@
+// Package witness builds SimpleVerifierCircuit assignments
+//
+// A Builder collects the dataset, handlers and ops of a job; Build zero pads
+// every input to the shape, encodes signed cells and fills DataRoot and the
+// public Results with native.Evaluate:
+//
+//	b := witness.New(cfg)
+//	b.SetColumn(1, witness.Column{Bits: 16, Signed: true})
+//	b.SetRows(witness.Ints(1, -5), witness.Ints(2, 7))
+//	h := b.AddHandler(0, 2)
+//	b.AddOp(h, lib.OP_MERKLE16)
+//	sum := b.AddOp(h, lib.OP_SUM_COL, 1)
+//	b.SetFilter(h, sum, &circuit.Filter{Pred: lib.PRED_GE, Col: 0, A: big.NewInt(2)})
+//	assignment, publicWitness, err := b.Build(ecc.BN254)
+package witness
+
+import (
+	"fmt"
+	"math/big"
+
+	"simple-verifier-gnark/pkg/circuit"
+	"simple-verifier-gnark/pkg/lib"
+	"simple-verifier-gnark/pkg/native"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	gnarkwitness "github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/frontend"
+)
+
+// Column is the type of one column (ColumnBits / ColumnSigned / ColumnScales)
+type Column struct {
+	// Bits: width in [1, VALUE_BITS] (0 = VALUE_BITS)
+	Bits int
+
+	// Signed: values are two's-complement integers of Bits width
+	Signed bool
+
+	// Scale: fixed-point scale (0 = 1, integer column)
+	Scale uint64
+}
+
+// Builder accumulates a job and builds its assignment
+// Setters record the first error, returned by Items, Assignment and Build.
+type Builder struct {
+	cfg      circuit.Config
+	columns  []Column
+	rows     [][]*big.Int
+	handlers []handler
+	err      error
+}
+
+// handler is a column range and its ops
+type handler struct {
+	start int
+	nc    int
+	ops   []op
+}
+
+// op is one op slot: opcode, column args, GROUP BY keys and row filter
+type op struct {
+	opCode int
+	x      int
+	y      int
+	groups []*big.Int
+	filter *circuit.Filter
+}
+
+// New returns an empty builder for a shape: no rows, every column a 64-bit integer
+func New(cfg circuit.Config) *Builder {
+	return &Builder{cfg: cfg, columns: make([]Column, cfg.MaxCols)}
+}
+
+// Ints converts int64 values (a row, group keys) to big integers
+func Ints(values ...int64) []*big.Int {
+	out := make([]*big.Int, len(values))
+
+	for i, v := range values {
+		out[i] = big.NewInt(v)
+	}
+
+	return out
+}
+
+// fail records err if no error was recorded yet
+func (b *Builder) fail(format string, args ...any) {
+	if b.err == nil {
+		b.err = fmt.Errorf("witness: "+format, args...)
+	}
+}
+
+// SetColumn sets the type of column col
+func (b *Builder) SetColumn(col int, column Column) {
+	if col < 0 || col >= b.cfg.MaxCols {
+		b.fail("column %d exceeds MAX_COLS=%d of %s", col, b.cfg.MaxCols, b.cfg)
+
+		return
+	}
+
+	if column.Bits < 0 || column.Bits > lib.VALUE_BITS {
+		b.fail("column %d: bits must be in [0, %d] (0 = default), got %d", col, lib.VALUE_BITS, column.Bits)
+
+		return
+	}
+
+	b.columns[col] = column
+}
+
+// SetRows replaces the dataset: one slice of values per row, in column order
+// Signed columns take negative values; cells are encoded and range checked by Build.
+func (b *Builder) SetRows(rows ...[]*big.Int) {
+	if len(rows) > b.cfg.MaxRows {
+		b.fail("%d rows exceed MAX_ROWS=%d of %s", len(rows), b.cfg.MaxRows, b.cfg)
+
+		return
+	}
+
+	for row, values := range rows {
+		if len(values) > b.cfg.MaxCols {
+			b.fail("row %d: %d columns exceed MAX_COLS=%d of %s", row, len(values), b.cfg.MaxCols, b.cfg)
+
+			return
+		}
+	}
+
+	b.rows = rows
+}
+
+// AddHandler appends a handler committing columns [start, start + nc) and returns its index
+func (b *Builder) AddHandler(start, nc int) int {
+	h := len(b.handlers)
+
+	if h >= b.cfg.MaxHandlers {
+		b.fail("handler %d exceeds MAX_HANDLERS=%d of %s", h, b.cfg.MaxHandlers, b.cfg)
+	}
+
+	if start < 0 || nc < 0 || start+nc > b.cfg.MaxCols {
+		b.fail("handler %d: columns [%d, %d) out of range", h, start, start+nc)
+	}
+
+	b.handlers = append(b.handlers, handler{start: start, nc: nc})
+
+	return h
+}
+
+// AddOp appends an op (lib.OP_*) with up to 2 column args (X, Y) to handler h
+// and returns its index in the handler
+func (b *Builder) AddOp(h, opCode int, args ...int) int {
+	if h < 0 || h >= len(b.handlers) {
+		b.fail("unknown handler %d", h)
+
+		return 0
+	}
+
+	ops := &b.handlers[h].ops
+
+	index := len(*ops)
+
+	if index >= b.cfg.MaxOps {
+		b.fail("handler %d: op %d exceeds MAX_OPS=%d of %s", h, index, b.cfg.MaxOps, b.cfg)
+	}
+
+	if !knownOpCode(opCode) {
+		b.fail("handler %d op %d: unknown opcode %d", h, index, opCode)
+	}
+
+	if len(args) > 2 {
+		b.fail("handler %d op %d: %d column args, at most 2", h, index, len(args))
+	}
+
+	o := op{opCode: opCode}
+
+	for i, col := range args {
+		if col < 0 || col >= b.cfg.MaxCols {
+			b.fail("handler %d op %d: column arg %d out of range", h, index, col)
+		}
+
+		if i == 0 {
+			o.x = col
+		} else {
+			o.y = col
+		}
+	}
+
+	*ops = append(*ops, o)
+
+	return index
+}
+
+// knownOpCode reports whether opCode is one of lib.OpCodeNames
+func knownOpCode(opCode int) bool {
+	for _, code := range lib.OpCodeNames {
+		if code == opCode {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SetGroups sets the GROUP BY keys of op [h][op] (strictly increasing, signed)
+func (b *Builder) SetGroups(h, op int, keys ...*big.Int) {
+	o := b.op(h, op)
+
+	if o == nil {
+		return
+	}
+
+	if len(keys) > b.cfg.MaxGroups {
+		b.fail("handler %d op %d: %d groups exceed MAX_GROUPS=%d of %s", h, op, len(keys), b.cfg.MaxGroups, b.cfg)
+
+		return
+	}
+
+	o.groups = keys
+}
+
+// SetFilter sets the row filter of op [h][op] (nil = every row, see circuit.SetFilter)
+func (b *Builder) SetFilter(h, op int, f *circuit.Filter) {
+	o := b.op(h, op)
+
+	if o == nil {
+		return
+	}
+
+	if f != nil && f.Leaves() > b.cfg.MaxPredicates {
+		b.fail("handler %d op %d: filter needs %d predicates, MAX_PREDICATES=%d of %s", h, op, f.Leaves(), b.cfg.MaxPredicates, b.cfg)
+
+		return
+	}
+
+	o.filter = f
+}
+
+// op returns op [h][op], or nil after recording an error
+func (b *Builder) op(h, index int) *op {
+	if h < 0 || h >= len(b.handlers) || index < 0 || index >= len(b.handlers[h].ops) {
+		b.fail("unknown op [%d][%d]", h, index)
+
+		return nil
+	}
+
+	return &b.handlers[h].ops[index]
+}
+
+// Items returns the encoded dataset as the Items matrix [col][row], zero padded
+func (b *Builder) Items() ([][]frontend.Variable, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	items := make([][]frontend.Variable, b.cfg.MaxCols)
+
+	for col := range items {
+		items[col] = make([]frontend.Variable, b.cfg.MaxRows)
+
+		for row := range items[col] {
+			items[col][row] = 0
+		}
+	}
+
+	for row, values := range b.rows {
+		for col, value := range values {
+			column := b.columns[col]
+
+			bits := column.Bits
+
+			if bits == 0 {
+				bits = lib.VALUE_BITS
+			}
+
+			cell, err := native.Encode(value, bits, column.Signed)
+
+			if err != nil {
+				return nil, fmt.Errorf("witness: row %d column %d: %w", row, col, err)
+			}
+
+			items[col][row] = cell
+		}
+	}
+
+	return items, nil
+}
+
+// Assignment builds the full assignment: every input zero padded to the shape,
+// DataRoot and Results evaluated off-chain (see native.Evaluate)
+func (b *Builder) Assignment(curve ecc.ID) (*circuit.SimpleVerifierCircuit, error) {
+	items, err := b.Items()
+
+	if err != nil {
+		return nil, err
+	}
+
+	c := circuit.New(b.cfg)
+
+	c.Items = items
+
+	c.NR = len(b.rows)
+
+	for col, column := range b.columns {
+		c.ColumnBits[col] = column.Bits
+
+		if column.Bits == 0 {
+			c.ColumnBits[col] = lib.VALUE_BITS
+		}
+
+		c.ColumnSigned[col] = 0
+
+		if column.Signed {
+			c.ColumnSigned[col] = 1
+		}
+
+		c.ColumnScales[col] = new(big.Int).SetUint64(max(column.Scale, 1))
+	}
+
+	field := curve.ScalarField()
+
+	c.NumHandlers = len(b.handlers)
+
+	for h, handler := range b.handlers {
+		c.HandlerStartIndex[h] = handler.start
+
+		c.HandlerNCs[h] = handler.nc
+
+		for index, o := range handler.ops {
+			c.OpCodes[h][index] = o.opCode
+
+			c.OpArgs[h][index] = [2]frontend.Variable{o.x, o.y}
+
+			c.NumGroups[h][index] = len(o.groups)
+
+			for g, key := range o.groups {
+				c.GroupKeys[h][index][g] = new(big.Int).Mod(key, field)
+			}
+
+			if err := c.SetFilter(h, index, o.filter); err != nil {
+				return nil, fmt.Errorf("witness: %w", err)
+			}
+		}
+	}
+
+	if err := native.Evaluate(c, curve); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Build returns the assignment and its public witness (see Assignment)
+func (b *Builder) Build(curve ecc.ID) (*circuit.SimpleVerifierCircuit, gnarkwitness.Witness, error) {
+	c, err := b.Assignment(curve)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	publicWitness, err := frontend.NewWitness(c, curve.ScalarField(), frontend.PublicOnly())
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("witness: %w", err)
+	}
+
+	return c, publicWitness, nil
+}
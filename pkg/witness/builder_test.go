@@ -0,0 +1,186 @@
+package witness_test
+
+import (
+	"bytes"
+	"math/big"
+	"strings"
+	"testing"
+
+	"simple-verifier-gnark/internal/sample"
+	"simple-verifier-gnark/pkg/circuit"
+	"simple-verifier-gnark/pkg/lib"
+	"simple-verifier-gnark/pkg/witness"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/test"
+)
+
+// example adds one handler to the sample dataset: the MERKLE16 root of every
+// column and the sum of delta over the rows with id >= 2
+func example() *witness.Builder {
+	b := sample.Dataset(sample.Shape())
+
+	h := b.AddHandler(0, 4)
+
+	b.AddOp(h, lib.OP_MERKLE16)
+
+	sum := b.AddOp(h, lib.OP_SUM_COL, 3)
+
+	b.SetFilter(h, sum, &circuit.Filter{Pred: lib.PRED_GE, Col: 0, A: big.NewInt(2)})
+
+	return b
+}
+
+func TestBuild(t *testing.T) {
+	curve := ecc.BN254
+
+	field := curve.ScalarField()
+
+	assignment, publicWitness, err := example().Build(curve)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// delta = -1 is stored as its 16-bit two's complement
+	if got := assignment.Items[3][3].(*big.Int); got.Cmp(big.NewInt(1<<16-1)) != 0 {
+		t.Errorf("Items[3][3] = %s, want %d", got, 1<<16-1)
+	}
+
+	if got, want := assignment.Results[0][1][0].(*big.Int), new(big.Int).Mod(big.NewInt(-48), field); got.Cmp(want) != 0 {
+		t.Errorf("SUM_COL WHERE id >= 2 = %s, want -48", got)
+	}
+
+	// Unused inputs are zero padded, not nil
+	if assignment.Items[3][15] == nil || assignment.GroupKeys[1][1][3] == nil || assignment.Results[1][1][3] == nil {
+		t.Error("unused inputs not padded")
+	}
+
+	want, err := frontend.NewWitness(assignment, field, frontend.PublicOnly())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := publicWitness.MarshalBinary()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := want.MarshalBinary()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(got, expected) {
+		t.Error("public witness differs from the public inputs of the assignment")
+	}
+
+	if err := test.IsSolved(circuit.New(sample.Shape()), assignment, field); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBuilderErrors(t *testing.T) {
+	cases := []struct {
+		name  string
+		build func(b *witness.Builder)
+		want  string
+	}{
+		{"column index", func(b *witness.Builder) {
+			b.SetColumn(4, witness.Column{})
+		}, "column 4 exceeds MAX_COLS=4"},
+		{"column bits", func(b *witness.Builder) {
+			b.SetColumn(0, witness.Column{Bits: 65})
+		}, "bits must be in [0, 64] (0 = default), got 65"},
+		{"too many rows", func(b *witness.Builder) {
+			b.SetRows(make([][]*big.Int, 17)...)
+		}, "17 rows exceed MAX_ROWS=16"},
+		{"too many columns", func(b *witness.Builder) {
+			b.SetRows(witness.Ints(1, 2, 3, 4, 5))
+		}, "row 0: 5 columns exceed MAX_COLS=4"},
+		{"cell out of range", func(b *witness.Builder) {
+			b.SetColumn(1, witness.Column{Bits: 8})
+
+			b.SetRows(witness.Ints(1, 256))
+		}, "row 0 column 1: 256 out of range [0, 256)"},
+		{"negative unsigned cell", func(b *witness.Builder) {
+			b.SetRows(witness.Ints(-1))
+		}, "row 0 column 0: -1 out of range"},
+		{"too many handlers", func(b *witness.Builder) {
+			for range 4 {
+				b.AddHandler(0, 1)
+			}
+		}, "handler 3 exceeds MAX_HANDLERS=3"},
+		{"handler columns", func(b *witness.Builder) {
+			b.AddHandler(2, 3)
+		}, "handler 0: columns [2, 5) out of range"},
+		{"unknown handler", func(b *witness.Builder) {
+			b.AddOp(0, lib.OP_COUNT)
+		}, "unknown handler 0"},
+		{"too many ops", func(b *witness.Builder) {
+			h := b.AddHandler(0, 1)
+
+			for range 5 {
+				b.AddOp(h, lib.OP_COUNT)
+			}
+		}, "handler 0: op 4 exceeds MAX_OPS=4"},
+		{"unknown opcode", func(b *witness.Builder) {
+			b.AddOp(b.AddHandler(0, 1), 42)
+		}, "handler 0 op 0: unknown opcode 42"},
+		{"too many args", func(b *witness.Builder) {
+			b.AddOp(b.AddHandler(0, 1), lib.OP_SUM_PRODUCT, 0, 1, 2)
+		}, "3 column args, at most 2"},
+		{"column arg", func(b *witness.Builder) {
+			b.AddOp(b.AddHandler(0, 1), lib.OP_SUM_COL, 4)
+		}, "column arg 4 out of range"},
+		{"unknown op", func(b *witness.Builder) {
+			b.SetGroups(b.AddHandler(0, 1), 0, witness.Ints(1)...)
+		}, "unknown op [0][0]"},
+		{"too many groups", func(b *witness.Builder) {
+			h := b.AddHandler(0, 1)
+
+			b.SetGroups(h, b.AddOp(h, lib.OP_COUNT_BY), witness.Ints(1, 2, 3, 4, 5)...)
+		}, "5 groups exceed MAX_GROUPS=4"},
+		{"too many predicates", func(b *witness.Builder) {
+			h := b.AddHandler(0, 1)
+
+			leaf := &circuit.Filter{Pred: lib.PRED_EQ, Col: 0, A: big.NewInt(1)}
+
+			b.SetFilter(h, b.AddOp(h, lib.OP_COUNT), &circuit.Filter{And: []*circuit.Filter{leaf, leaf, leaf}})
+		}, "filter needs 4 predicates, MAX_PREDICATES=2"},
+		{"unlisted group key", func(b *witness.Builder) {
+			b.SetRows(witness.Ints(1), witness.Ints(2))
+
+			h := b.AddHandler(0, 1)
+
+			b.SetGroups(h, b.AddOp(h, lib.OP_COUNT_BY), witness.Ints(1)...)
+		}, "group key 2 not listed"},
+		{"first error wins", func(b *witness.Builder) {
+			b.SetColumn(9, witness.Column{})
+
+			b.AddOp(5, lib.OP_COUNT)
+		}, "column 9 exceeds"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			b := witness.New(sample.Shape())
+
+			tc.build(b)
+
+			_, _, err := b.Build(ecc.BN254)
+
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("error %v, want %q", err, tc.want)
+			}
+
+			if !strings.HasPrefix(err.Error(), "witness: ") && !strings.HasPrefix(err.Error(), "native: ") {
+				t.Errorf("error %q lacks a package prefix", err)
+			}
+		})
+	}
+}